@@ -5,6 +5,7 @@ go 1.24
 require (
 	github.com/aws/aws-sdk-go v1.55.7
 	github.com/labstack/echo/v4 v4.13.4
+	golang.org/x/net v0.41.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -16,7 +17,6 @@ require (
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	golang.org/x/crypto v0.39.0 // indirect
-	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	golang.org/x/time v0.12.0 // indirect
@@ -8,6 +8,7 @@ import (
 	"github.com/tekig/photo-backup-server/internal/gateway/http"
 	"github.com/tekig/photo-backup-server/internal/photo"
 	"github.com/tekig/photo-backup-server/internal/repository/cmd"
+	"github.com/tekig/photo-backup-server/internal/repository/geonames"
 	"github.com/tekig/photo-backup-server/internal/repository/s3"
 	"gopkg.in/yaml.v2"
 )
@@ -30,7 +31,10 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
 
-	thumbnails := cmd.New()
+	commands := cmd.New(cmd.CMDConfig{
+		ICCProfile:     config.Commands.ICCProfile,
+		SpriteInterval: config.Commands.SpriteInterval,
+	})
 	storage, err := s3.New(s3.StorageConfig{
 		Endpoint:     config.Storage.Endpoint,
 		AccessKey:    config.Storage.AccessKey,
@@ -42,14 +46,31 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("new s3 storage: %w", err)
 	}
 
-	usecase, err := photo.New(storage, thumbnails)
+	geocoder, err := geonames.New(geonames.GeoNamesConfig{
+		Cities:    config.Geocoder.Cities,
+		Admin1:    config.Geocoder.Admin1,
+		Countries: config.Geocoder.Countries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new geonames: %w", err)
+	}
+
+	usecase, err := photo.New(storage, commands, commands, geocoder, commands)
 	if err != nil {
 		return nil, fmt.Errorf("new photo: %w", err)
 	}
 
 	gateway := http.New(http.GatewayConfig{
-		Photo:   usecase,
-		Address: config.Gateway.Address,
+		Photo:            usecase,
+		Address:          config.Gateway.Address,
+		UserHeader:       config.Gateway.UserHeader,
+		AllowedOrigins:   config.Gateway.AllowedOrigins,
+		UploadDir:        config.Gateway.Uploads.Dir,
+		UploadExpiration: config.Gateway.Uploads.Expiration,
+		UploadMaxSize:    config.Gateway.Uploads.MaxSize,
+
+		CacheControlOriginal:  config.Gateway.CacheControl.Original,
+		CacheControlThumbnail: config.Gateway.CacheControl.Thumbnail,
 	})
 
 	return &App{
@@ -1,9 +1,32 @@
 package app
 
+import "time"
+
 type Config struct {
 	Gateway struct {
-		Address string `yaml:"Address"`
+		Address    string `yaml:"Address"`
+		UserHeader string `yaml:"UserHeader"`
+		// AllowedOrigins may open WebSockets besides the origin of the host.
+		AllowedOrigins []string `yaml:"AllowedOrigins"`
+		Uploads        struct {
+			Dir        string        `yaml:"Dir"`
+			Expiration time.Duration `yaml:"Expiration"`
+			MaxSize    int64         `yaml:"MaxSize"`
+		} `yaml:"Uploads"`
+		CacheControl struct {
+			Original  string `yaml:"Original"`
+			Thumbnail string `yaml:"Thumbnail"`
+		} `yaml:"CacheControl"`
 	} `yaml:"Gateway"`
+	Commands struct {
+		ICCProfile     string        `yaml:"ICCProfile"`
+		SpriteInterval time.Duration `yaml:"SpriteInterval"`
+	} `yaml:"Commands"`
+	Geocoder struct {
+		Cities    string `yaml:"Cities"`
+		Admin1    string `yaml:"Admin1"`
+		Countries string `yaml:"Countries"`
+	} `yaml:"Geocoder"`
 	Storage struct {
 		Endpoint     string `yaml:"Endpoint"`
 		AccessKey    string `yaml:"AccessKey"`
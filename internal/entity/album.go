@@ -0,0 +1,24 @@
+package entity
+
+type Album struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Items are content IDs in display order. Items of smart albums are
+	// evaluated from the query on every read and are not stored.
+	Items    []string `json:"items"`
+	Query    *Query   `json:"query,omitempty"`
+	Cover    string   `json:"cover,omitempty"`
+	Created  int64    `json:"created"`
+	Modified int64    `json:"modified"`
+}
+
+type AlbumContents struct {
+	Album
+	Contents []Content `json:"contents"`
+}
+
+type AlbumUpdate struct {
+	Name  *string `json:"name,omitempty"`
+	Cover *string `json:"cover,omitempty"`
+	Query *Query  `json:"query,omitempty"`
+}
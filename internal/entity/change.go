@@ -0,0 +1,29 @@
+package entity
+
+type ChangeType string
+
+const (
+	ChangeUpsert ChangeType = "upsert"
+	ChangeDelete ChangeType = "delete"
+)
+
+// Change is a single entry of the delta feed. Content is set for upserts only.
+type Change struct {
+	Seq     int64      `json:"seq"`
+	Type    ChangeType `json:"type"`
+	ID      string     `json:"id"`
+	Content *Content   `json:"content,omitempty"`
+}
+
+type Changes struct {
+	Changes []Change `json:"changes"`
+	Cursor  int64    `json:"cursor"`
+	More    bool     `json:"more"`
+}
+
+// Tombstone remembers a deleted content so clients can drop it from their mirror.
+type Tombstone struct {
+	ID      string `json:"id"`
+	Seq     int64  `json:"seq"`
+	Deleted int64  `json:"deleted"`
+}
@@ -1,16 +1,130 @@
 package entity
 
-import "io"
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
 
 type Content struct {
+	Seq       int64  `json:"seq,omitempty"`
 	Original  Object `json:"original,omitempty"`
 	Thumbnail Object `json:"thumbnail,omitempty"`
+	// Poster is a still frame of videos, their thumbnail is an animation.
+	Poster Object `json:"poster,omitzero"`
+	// Display is a browser friendly image of originals browsers cannot
+	// render, such as HEIC and RAW, set once created in the background.
+	Display Object `json:"display,omitzero"`
+	// Sprites are the hover scrubbing previews of long videos, set once
+	// created in the background.
+	Sprites *Sprites `json:"sprites,omitempty"`
+	// Stream is the HLS master playlist of videos, set once transcoded in
+	// the background.
+	Stream   Object    `json:"stream,omitzero"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+	// Place is reverse geocoded from the metadata location.
+	Place *Place `json:"place,omitempty"`
+	// Motion is the video part of a live or motion photo: the original of the
+	// paired content or the video extracted from the still.
+	Motion Object `json:"motion,omitzero"`
+	// Pair links the still and the video of a live photo uploaded as two
+	// contents, it is set on both.
+	Pair string `json:"pair,omitempty"`
+	// Stack groups RAW and JPEG siblings of one shot, it is shared by all of
+	// them.
+	Stack string `json:"stack,omitempty"`
+	// Owner is the user who uploaded the content first, empty without an
+	// authorization proxy and for contents uploaded before owners were kept.
+	Owner string `json:"owner,omitempty"`
+	UserMetadata
+}
+
+// UserMetadata is authored by users and survives re-uploads of the original.
+type UserMetadata struct {
+	Favorite bool `json:"favorite,omitempty"`
+	// Rating is 0 for unrated up to 5.
+	Rating  int      `json:"rating,omitempty"`
+	Caption string   `json:"caption,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Hidden  bool     `json:"hidden,omitempty"`
+	// LocationPrivate shows the location and place to the owner only.
+	LocationPrivate bool `json:"location_private,omitempty"`
+}
+
+// UserMetadataUpdate is a partial update, nil fields are left unchanged.
+type UserMetadataUpdate struct {
+	Favorite *bool     `json:"favorite,omitempty"`
+	Rating   *int      `json:"rating,omitempty"`
+	Caption  *string   `json:"caption,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+	Hidden   *bool     `json:"hidden,omitempty"`
+
+	LocationPrivate *bool `json:"location_private,omitempty"`
+}
+
+// ValidateID rejects content IDs that are not a single path element, IDs
+// name storage objects and staged files.
+func ValidateID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return fmt.Errorf("id `%s`: %w", id, ErrInvalidInput)
+	}
+
+	return nil
+}
+
+// VisibleTo returns the content as the viewer may see it, without a private
+// location unless the viewer owns it.
+func (c Content) VisibleTo(v Viewer) Content {
+	if !c.LocationPrivate || v.Owns(c) {
+		return c
+	}
+
+	if c.Metadata != nil {
+		metadata := *c.Metadata
+		metadata.Location = nil
+		c.Metadata = &metadata
+	}
+	c.Place = nil
+
+	return c
+}
+
+// CapturedAt is the best known capture time of the content in unix seconds,
+// the file modification time is used when the original has no capture date.
+func (c Content) CapturedAt() int64 {
+	if c.Metadata != nil && c.Metadata.CapturedAt != 0 {
+		return c.Metadata.CapturedAt
+	}
+
+	return c.Original.LastModified
+}
+
+// Sprites are sheets of tiled video frames with a WebVTT index referencing
+// sheet n as `sprites/n`, counting from 1.
+type Sprites struct {
+	Index  Object   `json:"index"`
+	Sheets []Object `json:"sheets"`
 }
 
 type Object struct {
 	ID           string `json:"id,omitempty"`
 	ContentType  string `json:"content_type,omitempty"`
 	LastModified int64  `json:"last_modified,omitempty"`
+	// Hash is the hex SHA-256 of the object data.
+	Hash string `json:"hash,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// ETag is strong for hashed objects and weak for objects stored before
+// hashing was introduced.
+func (o Object) ETag() string {
+	if o.Hash != "" {
+		return `"` + o.Hash + `"`
+	}
+
+	return fmt.Sprintf(`W/"%s-%d"`, o.ID, o.LastModified)
 }
 
 type ObjectReader struct {
@@ -23,5 +137,30 @@ type ObjectReader struct {
 type ObjectRequest struct {
 	ID              string
 	IfModifiedSince *int64
+	IfNoneMatch     *string
+	IfMatch         *string
+	IfRangeETag     *string
+	IfRangeModified *int64
 	Range           *string
 }
+
+// Selection picks contents by ID or by capture time. Bounds are inclusive.
+type Selection struct {
+	IDs  []string   `json:"ids,omitempty"`
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+func (s Selection) Match(c Content) bool {
+	if len(s.IDs) != 0 && !slices.Contains(s.IDs, c.Original.ID) {
+		return false
+	}
+	if s.From != nil && c.CapturedAt() < s.From.Unix() {
+		return false
+	}
+	if s.To != nil && c.CapturedAt() > s.To.Unix() {
+		return false
+	}
+
+	return true
+}
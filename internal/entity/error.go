@@ -3,6 +3,16 @@ package entity
 import "errors"
 
 var (
-	ErrNotFound    = errors.New("not found")
-	ErrNotModified = errors.New("not modified")
+	ErrNotFound            = errors.New("not found")
+	ErrGone                = errors.New("gone")
+	ErrResyncRequired      = errors.New("full resync required")
+	ErrNotModified         = errors.New("not modified")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+	ErrConflict            = errors.New("conflict")
+	ErrForbidden           = errors.New("forbidden")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrUnsupportedMedia    = errors.New("unsupported media")
+	ErrQuotaExceeded       = errors.New("quota exceeded")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
 )
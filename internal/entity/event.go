@@ -0,0 +1,62 @@
+package entity
+
+import "context"
+
+type EventType string
+
+const (
+	EventUpload    EventType = "upload"
+	EventThumbnail EventType = "thumbnail"
+	EventDelete    EventType = "delete"
+	EventMetadata  EventType = "metadata"
+	EventStream    EventType = "stream"
+	EventSprites   EventType = "sprites"
+	EventDisplay   EventType = "display"
+)
+
+type Event struct {
+	ID        int64     `json:"id"`
+	Type      EventType `json:"type"`
+	ContentID string    `json:"content_id"`
+	User      string    `json:"user,omitempty"`
+	Time      int64     `json:"time"`
+	Content   *Content  `json:"content,omitempty"`
+}
+
+type userKey struct{}
+
+// WithUser attaches the authenticated user, as reported by the proxy in front
+// of the server, to the context. An empty user is a request the proxy let
+// through without one.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+
+	return user
+}
+
+// Viewer is who a response is for.
+type Viewer struct {
+	User string
+	// Anonymous is a request without a user on a server with an
+	// authorization proxy, it owns nothing.
+	Anonymous bool
+}
+
+func ViewerFromContext(ctx context.Context) Viewer {
+	user, ok := ctx.Value(userKey{}).(string)
+
+	return Viewer{
+		User:      user,
+		Anonymous: ok && user == "",
+	}
+}
+
+// Owns reports whether the viewer uploaded the content. Without an
+// authorization proxy the only user owns everything.
+func (v Viewer) Owns(c Content) bool {
+	return !v.Anonymous && v.User == c.Owner
+}
@@ -0,0 +1,36 @@
+package entity
+
+// MaxZoom is the deepest web map zoom level clustering is computed for.
+const MaxZoom = 22
+
+type GeoRequest struct {
+	Bounds Bounds
+	// Zoom enables grid clustering for the web map zoom level, nil returns
+	// every point.
+	Zoom *int
+	// Shared leaves out private locations the viewer owns as well, others
+	// never see them.
+	Shared        bool
+	ExcludeHidden bool
+}
+
+// GeoCluster is a group of contents in one grid cell. Location is the
+// centroid of the members, Content is set when the cluster has one member.
+type GeoCluster struct {
+	Location Location `json:"location"`
+	Bounds   Bounds   `json:"bounds"`
+	Count    int      `json:"count"`
+	Content  *Content `json:"content,omitempty"`
+}
+
+// Add extends the cluster with a member location.
+func (g *GeoCluster) Add(l Location) {
+	g.Count++
+	g.Location.Latitude += (l.Latitude - g.Location.Latitude) / float64(g.Count)
+	g.Location.Longitude += (l.Longitude - g.Location.Longitude) / float64(g.Count)
+
+	g.Bounds.MinLatitude = min(g.Bounds.MinLatitude, l.Latitude)
+	g.Bounds.MinLongitude = min(g.Bounds.MinLongitude, l.Longitude)
+	g.Bounds.MaxLatitude = max(g.Bounds.MaxLatitude, l.Latitude)
+	g.Bounds.MaxLongitude = max(g.Bounds.MaxLongitude, l.Longitude)
+}
@@ -0,0 +1,42 @@
+package entity
+
+// rawTypes are camera RAW formats with their usual extension.
+var rawTypes = map[string]string{
+	"image/x-adobe-dng":     ".dng",
+	"image/dng":             ".dng",
+	"image/x-canon-cr2":     ".cr2",
+	"image/x-canon-cr3":     ".cr3",
+	"image/x-nikon-nef":     ".nef",
+	"image/x-nikon-nrw":     ".nrw",
+	"image/x-sony-arw":      ".arw",
+	"image/x-fuji-raf":      ".raf",
+	"image/x-panasonic-rw2": ".rw2",
+	"image/x-olympus-orf":   ".orf",
+	"image/x-pentax-pef":    ".pef",
+}
+
+// IsRaw reports whether the content type is a camera RAW format.
+func IsRaw(contentType string) bool {
+	_, ok := rawTypes[contentType]
+	return ok
+}
+
+// RawExtension is the file extension of a RAW content type.
+func RawExtension(contentType string) (string, bool) {
+	ext, ok := rawTypes[contentType]
+	return ext, ok
+}
+
+// webTypes are image formats browsers render natively.
+var webTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/avif": true,
+}
+
+// IsWebImage reports whether browsers can display the content type as is.
+func IsWebImage(contentType string) bool {
+	return webTypes[contentType]
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+type Memory struct {
+	Year     int       `json:"year"`
+	YearsAgo int       `json:"years_ago"`
+	Contents []Content `json:"contents"`
+}
+
+type MemoriesRequest struct {
+	// Date is the day to look back from, its location defines the calendar day.
+	Date               time.Time
+	ExcludeHidden      bool
+	ExcludeScreenshots bool
+}
@@ -0,0 +1,48 @@
+package entity
+
+// Metadata is extracted from the original on upload.
+type Metadata struct {
+	CapturedAt int64     `json:"captured_at,omitempty"`
+	Make       string    `json:"make,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	Duration   float64   `json:"duration,omitempty"`
+	Location   *Location `json:"location,omitempty"`
+	// ContentIdentifier is shared by the still and the video of an Apple
+	// Live Photo.
+	ContentIdentifier string `json:"content_identifier,omitempty"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Place is the nearest known city of a location.
+type Place struct {
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// Bounds is a latitude/longitude box. A box crossing the antimeridian has
+// MinLongitude greater than MaxLongitude.
+type Bounds struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+func (b Bounds) Contains(l Location) bool {
+	if l.Latitude < b.MinLatitude || l.Latitude > b.MaxLatitude {
+		return false
+	}
+
+	if b.MinLongitude <= b.MaxLongitude {
+		return l.Longitude >= b.MinLongitude && l.Longitude <= b.MaxLongitude
+	}
+
+	return l.Longitude >= b.MinLongitude || l.Longitude <= b.MaxLongitude
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Query is a saved search behind a smart album. Every set criterion must match.
+type Query struct {
+	// ContentType is a full type such as "image/png" or a class such as "video".
+	ContentType string     `json:"content_type,omitempty"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	// Camera matches make and model, case-insensitive.
+	Camera   string   `json:"camera,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Bounds   *Bounds  `json:"bounds,omitempty"`
+	Favorite *bool    `json:"favorite,omitempty"`
+}
+
+func (q Query) Match(c Content) bool {
+	if q.ContentType != "" {
+		if strings.Contains(q.ContentType, "/") {
+			if c.Original.ContentType != q.ContentType {
+				return false
+			}
+		} else if !strings.HasPrefix(c.Original.ContentType, q.ContentType+"/") {
+			return false
+		}
+	}
+
+	if q.From != nil && c.CapturedAt() < q.From.Unix() {
+		return false
+	}
+	if q.To != nil && c.CapturedAt() > q.To.Unix() {
+		return false
+	}
+
+	if q.Camera != "" {
+		if c.Metadata == nil {
+			return false
+		}
+
+		camera := strings.ToLower(c.Metadata.Make + " " + c.Metadata.Model)
+		if !strings.Contains(camera, strings.ToLower(q.Camera)) {
+			return false
+		}
+	}
+
+	for _, tag := range q.Tags {
+		if !slices.ContainsFunc(c.Tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+			return false
+		}
+	}
+
+	if q.Bounds != nil {
+		if c.Metadata == nil || c.Metadata.Location == nil || !q.Bounds.Contains(*c.Metadata.Location) {
+			return false
+		}
+	}
+
+	if q.Favorite != nil && c.Favorite != *q.Favorite {
+		return false
+	}
+
+	return true
+}
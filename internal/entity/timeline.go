@@ -0,0 +1,19 @@
+package entity
+
+type Granularity string
+
+const (
+	GranularityYear  Granularity = "year"
+	GranularityMonth Granularity = "month"
+	GranularityDay   Granularity = "day"
+)
+
+type TimelineBucket struct {
+	// Key is the bucket date formatted as 2006, 2006-01 or 2006-01-02.
+	Key   string `json:"key"`
+	Start int64  `json:"start"`
+	Count int    `json:"count"`
+	Bytes int64  `json:"bytes"`
+	// Types counts contents per media class, e.g. image or video.
+	Types map[string]int `json:"types"`
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+type albumCreateRequest struct {
+	Name  string        `json:"name"`
+	Query *entity.Query `json:"query,omitempty"`
+}
+
+type albumItemsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+func (g *Gateway) hdlrAlbums(c echo.Context) error {
+	albums, err := g.photo.Albums(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("albums: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, albums)
+}
+
+func (g *Gateway) hdlrAlbum(c echo.Context) error {
+	id, err := paramID(c)
+	if err != nil {
+		return fmt.Errorf("param id: %w", err)
+	}
+
+	album, err := g.photo.Album(c.Request().Context(), id)
+	if err != nil {
+		return fmt.Errorf("album: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, album)
+}
+
+func (g *Gateway) hdlrAlbumCreate(c echo.Context) error {
+	var req albumCreateRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("bind: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	album, err := g.photo.AlbumCreate(c.Request().Context(), req.Name, req.Query)
+	if err != nil {
+		return fmt.Errorf("album create: %w", err)
+	}
+
+	return c.JSON(http.StatusCreated, album)
+}
+
+func (g *Gateway) hdlrAlbumUpdate(c echo.Context) error {
+	id, err := paramID(c)
+	if err != nil {
+		return fmt.Errorf("param id: %w", err)
+	}
+
+	var req entity.AlbumUpdate
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("bind: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	album, err := g.photo.AlbumUpdate(c.Request().Context(), id, req)
+	if err != nil {
+		return fmt.Errorf("album update: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, album)
+}
+
+func (g *Gateway) hdlrAlbumDelete(c echo.Context) error {
+	id, err := paramID(c)
+	if err != nil {
+		return fmt.Errorf("param id: %w", err)
+	}
+
+	if err := g.photo.AlbumDelete(c.Request().Context(), id); err != nil {
+		return fmt.Errorf("album delete: %w", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (g *Gateway) hdlrAlbumItemsAdd(c echo.Context) error {
+	return g.albumItems(c, g.photo.AlbumAdd)
+}
+
+func (g *Gateway) hdlrAlbumItemsRemove(c echo.Context) error {
+	return g.albumItems(c, g.photo.AlbumRemove)
+}
+
+func (g *Gateway) hdlrAlbumItemsOrder(c echo.Context) error {
+	return g.albumItems(c, g.photo.AlbumOrder)
+}
+
+func (g *Gateway) albumItems(c echo.Context, modify func(ctx context.Context, id string, items []string) (*entity.Album, error)) error {
+	id, err := paramID(c)
+	if err != nil {
+		return fmt.Errorf("param id: %w", err)
+	}
+
+	var req albumItemsRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("bind: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	album, err := modify(c.Request().Context(), id, req.IDs)
+	if err != nil {
+		return fmt.Errorf("album items: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, album)
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const archiveDateLayout = "2006-01-02"
+
+// hdlrArchive streams a ZIP of the selected originals. The selection comes
+// from the query (id, from, to) on GET or from a JSON body on POST, which
+// suits long ID lists.
+func (g *Gateway) hdlrArchive(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var sel entity.Selection
+	switch c.Request().Method {
+	case http.MethodPost:
+		if err := c.Bind(&sel); err != nil {
+			return fmt.Errorf("bind: %w: %w", entity.ErrInvalidInput, err)
+		}
+	default:
+		var err error
+		sel.IDs = c.QueryParams()["id"]
+		if sel.From, err = queryTime(c, "from", false); err != nil {
+			return fmt.Errorf("query from: %w", err)
+		}
+		if sel.To, err = queryTime(c, "to", true); err != nil {
+			return fmt.Errorf("query to: %w", err)
+		}
+	}
+
+	if len(sel.IDs) == 0 && sel.From == nil && sel.To == nil {
+		return fmt.Errorf("empty selection: %w", entity.ErrInvalidInput)
+	}
+
+	contents, err := g.photo.Select(ctx, sel)
+	if err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+	if len(contents) == 0 {
+		return fmt.Errorf("select: %w", entity.ErrNotFound)
+	}
+
+	name := fmt.Sprintf("photos-%s.zip", time.Now().UTC().Format(archiveDateLayout))
+	c.Response().Header().Set("Content-Type", "application/zip")
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// Headers are sent, an error past this point can only abort the stream.
+	if err := g.photo.Archive(ctx, contents, c.Response()); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+
+	return nil
+}
+
+// queryTime accepts RFC 3339 timestamps or plain dates. A plain date used as
+// an upper bound covers the whole day.
+func queryTime(c echo.Context, name string, upper bool) (*time.Time, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(archiveDateLayout, v)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w: %w", entity.ErrInvalidInput, err)
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const batchConcurrency = 4
+
+const (
+	batchStatusOK    = "ok"
+	batchStatusError = "error"
+)
+
+type batchResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type batchReport struct {
+	Results []batchResult `json:"results"`
+}
+
+type batchItem struct {
+	object entity.Object
+	file   *os.File
+}
+
+// hdlrContentBatchUpload accepts multipart/form-data where each part is a
+// content: the ID is taken from the Content-ID part header or the file name,
+// the type from Content-Type and the modification time from Last-Modified.
+// Parts are spooled to disk and uploaded concurrently, failures are reported
+// per item instead of failing the whole batch.
+func (g *Gateway) hdlrContentBatchUpload(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	defer c.Request().Body.Close()
+
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return fmt.Errorf("multipart reader: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	tmp, err := os.MkdirTemp("", "batch-*")
+	if err != nil {
+		return fmt.Errorf("mkdir temp: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var (
+		results = make([]batchResult, 0)
+		items   = make([]*batchItem, 0)
+	)
+	defer func() {
+		for _, item := range items {
+			if item != nil {
+				item.file.Close()
+			}
+		}
+	}()
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("next part: %w", err)
+		}
+
+		item, err := spoolPart(tmp, len(items), part)
+		part.Close()
+		if err != nil {
+			logInternal(c, err)
+			results = append(results, batchResult{
+				ID:     item.object.ID,
+				Status: batchStatusError,
+				Code:   errorCode(err),
+				Error:  errorMessage(err),
+			})
+			items = append(items, nil)
+			continue
+		}
+
+		results = append(results, batchResult{ID: item.object.ID})
+		items = append(items, item)
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, batchConcurrency)
+	)
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := g.photo.ContentUpload(ctx, entity.ObjectReader{
+				Object:  item.object,
+				Content: item.file,
+			}); err != nil {
+				logInternal(c, err)
+				results[i].Status = batchStatusError
+				results[i].Code = errorCode(err)
+				results[i].Error = errorMessage(err)
+				return
+			}
+
+			results[i].Status = batchStatusOK
+		}()
+	}
+	wg.Wait()
+
+	return c.JSON(http.StatusOK, batchReport{
+		Results: results,
+	})
+}
+
+// spoolPart always returns an item so that the ID can be reported on error.
+func spoolPart(dir string, n int, part *multipart.Part) (*batchItem, error) {
+	var item = &batchItem{}
+
+	id, err := partID(part)
+	if err != nil {
+		return item, fmt.Errorf("part id: %w: %w", entity.ErrInvalidInput, err)
+	}
+	item.object.ID = id
+
+	if err := entity.ValidateID(id); err != nil {
+		return item, fmt.Errorf("part id: %w", err)
+	}
+
+	item.object.ContentType = part.Header.Get("Content-Type")
+	if item.object.ContentType == "" {
+		return item, fmt.Errorf("content type: %w: %w", entity.ErrInvalidInput, errEmptyValue)
+	}
+
+	lastModified, err := fromModifiedSince(part.Header.Get("Last-Modified"))
+	if err != nil {
+		return item, fmt.Errorf("last modified: %w: %w", entity.ErrInvalidInput, err)
+	}
+	item.object.LastModified = *lastModified
+
+	f, err := os.CreateTemp(dir, fmt.Sprintf("part-%d-*", n))
+	if err != nil {
+		return item, fmt.Errorf("create temp: %w", err)
+	}
+
+	if _, err := io.Copy(f, part); err != nil {
+		f.Close()
+		return item, fmt.Errorf("copy: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return item, fmt.Errorf("seek: %w", err)
+	}
+	item.file = f
+
+	return item, nil
+}
+
+func partID(part *multipart.Part) (string, error) {
+	if v := part.Header.Get("Content-ID"); v != "" {
+		return v, nil
+	}
+
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil {
+		return "", fmt.Errorf("parse content disposition: %w", err)
+	}
+
+	// Unlike part.FileName the raw parameter keeps directories, a path is
+	// rejected instead of being merged with files of the same name.
+	return params["filename"], nil
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const codeInternal = "internal"
+
+var errorStatuses = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{entity.ErrNotFound, http.StatusNotFound, "not_found"},
+	{entity.ErrGone, http.StatusGone, "gone"},
+	{entity.ErrResyncRequired, http.StatusGone, "resync_required"},
+	{entity.ErrNotModified, http.StatusNotModified, "not_modified"},
+	{entity.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{entity.ErrRangeNotSatisfiable, http.StatusRequestedRangeNotSatisfiable, "range_not_satisfiable"},
+	{entity.ErrConflict, http.StatusConflict, "conflict"},
+	{entity.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{entity.ErrInvalidInput, http.StatusBadRequest, "invalid_input"},
+	{entity.ErrUnsupportedMedia, http.StatusUnsupportedMediaType, "unsupported_media"},
+	{entity.ErrQuotaExceeded, http.StatusRequestEntityTooLarge, "quota_exceeded"},
+	{entity.ErrUpstreamUnavailable, http.StatusServiceUnavailable, "upstream_unavailable"},
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorHandler renders every handler error as a JSON body with a stable code.
+// Internal failures are logged but not exposed to the client.
+func errorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, code := toHTTPError(err)
+	message := errorMessage(err)
+	logInternal(c, err)
+
+	var resErr error
+	switch {
+	case status == http.StatusNotModified, c.Request().Method == http.MethodHead:
+		resErr = c.NoContent(status)
+	default:
+		resErr = c.JSON(status, errorResponse{
+			Error: errorBody{
+				Code:      code,
+				Message:   message,
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+			},
+		})
+	}
+	if resErr != nil {
+		c.Logger().Error(resErr)
+	}
+}
+
+func toHTTPError(err error) (int, string) {
+	for _, s := range errorStatuses {
+		if errors.Is(err, s.err) {
+			return s.status, s.code
+		}
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code, httpCode(he.Code)
+	}
+
+	return http.StatusInternalServerError, codeInternal
+}
+
+// errorMessage is the text of the domain error err wraps, the causes may
+// carry storage and tool details not meant for clients. Server errors only
+// get their status text.
+func errorMessage(err error) string {
+	status, _ := toHTTPError(err)
+	if status >= http.StatusInternalServerError {
+		return http.StatusText(status)
+	}
+
+	for _, s := range errorStatuses {
+		if errors.Is(err, s.err) {
+			return s.err.Error()
+		}
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		if m, ok := he.Message.(string); ok {
+			return m
+		}
+	}
+
+	return http.StatusText(status)
+}
+
+// logInternal logs the server errors clients only see the status text of.
+func logInternal(c echo.Context, err error) {
+	if status, _ := toHTTPError(err); status >= http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+}
+
+func errorCode(err error) string {
+	_, code := toHTTPError(err)
+
+	return code
+}
+
+// httpCode names errors raised by echo itself, e.g. unknown routes.
+func httpCode(status int) string {
+	for _, s := range errorStatuses {
+		if s.status == status {
+			return s.code
+		}
+	}
+	if status >= http.StatusInternalServerError {
+		return codeInternal
+	}
+
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
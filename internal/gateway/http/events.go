@@ -0,0 +1,180 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"golang.org/x/net/websocket"
+)
+
+const eventsHeartbeat = 30 * time.Second
+
+func (g *Gateway) hdlrEvents(c echo.Context) error {
+	lastID, err := lastEventID(c)
+	if err != nil {
+		return fmt.Errorf("last event id: %w", err)
+	}
+
+	sub := g.photo.Subscribe(c.Request().Context(), lastID)
+	defer sub.Close()
+
+	w := c.Response()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	for _, e := range sub.Replay {
+		if err := writeEvent(w, e); err != nil {
+			return nil
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case e, ok := <-sub.Events:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(w, e); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+	}
+}
+
+func (g *Gateway) hdlrEventsWebSocket(c echo.Context) error {
+	lastID, err := lastEventID(c)
+	if err != nil {
+		return fmt.Errorf("last event id: %w", err)
+	}
+
+	ctx := c.Request().Context()
+
+	websocket.Server{
+		Handshake: g.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			sub := g.photo.Subscribe(ctx, lastID)
+			defer sub.Close()
+
+			// Clients are not expected to send anything, reading only detects close.
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var msg string
+				for websocket.Message.Receive(ws, &msg) == nil {
+				}
+			}()
+
+			for _, e := range sub.Replay {
+				if err := websocket.JSON.Send(ws, e); err != nil {
+					return
+				}
+			}
+
+			for {
+				select {
+				case <-closed:
+					return
+				case e, ok := <-sub.Events:
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(ws, e); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}.ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+// checkOrigin refuses WebSockets opened by other sites, browsers send the
+// cookies of the proxy along. Clients that are not browsers send no Origin.
+func (g *Gateway) checkOrigin(config *websocket.Config, req *http.Request) error {
+	v := req.Header.Get("Origin")
+	if v == "" {
+		return nil
+	}
+
+	origin, err := url.Parse(v)
+	if err != nil {
+		return fmt.Errorf("parse origin: %w", err)
+	}
+	if origin.Host == req.Host || slices.Contains(g.origins, v) {
+		config.Origin = origin
+		return nil
+	}
+
+	return fmt.Errorf("origin `%s`: %w", v, entity.ErrForbidden)
+}
+
+func writeEvent(w *echo.Response, e entity.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+func lastEventID(c echo.Context) (int64, error) {
+	v := c.Request().Header.Get("Last-Event-ID")
+	if v == "" {
+		v = c.QueryParam("last_event_id")
+	}
+	if v == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	return id, nil
+}
+
+// userContext stores the user reported by the authorization proxy so that
+// events and private locations can be filtered per user. A request without
+// the header is stored as anonymous.
+func (g *Gateway) userContext(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if g.userHeader == "" {
+			return next(c)
+		}
+
+		user := c.Request().Header.Get(g.userHeader)
+		c.SetRequest(c.Request().WithContext(entity.WithUser(c.Request().Context(), user)))
+
+		return next(c)
+	}
+}
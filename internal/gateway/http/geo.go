@@ -0,0 +1,141 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const mimeGeoJSON = "application/geo+json"
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type string `json:"type"`
+	// BBox is west, south, east, north of a cluster.
+	BBox       []float64      `json:"bbox,omitempty"`
+	Geometry   geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type string `json:"type"`
+	// Coordinates are longitude, latitude as GeoJSON requires.
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// hdlrGeo returns located contents as a GeoJSON FeatureCollection. The box is
+// `bbox=west,south,east,north`, `zoom` clusters points per map zoom level and
+// `shared=true` leaves out the private locations of the user too, other users
+// never get them. `exclude_hidden=true` leaves out hidden contents as for
+// memories.
+func (g *Gateway) hdlrGeo(c echo.Context) error {
+	var req entity.GeoRequest
+
+	bounds, err := queryBBox(c)
+	if err != nil {
+		return fmt.Errorf("query bbox: %w", err)
+	}
+	req.Bounds = bounds
+
+	if v := c.QueryParam("zoom"); v != "" {
+		zoom, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("query zoom: %w: %w", entity.ErrInvalidInput, err)
+		}
+		req.Zoom = &zoom
+	}
+
+	if req.Shared, err = queryBool(c, "shared"); err != nil {
+		return fmt.Errorf("query shared: %w", err)
+	}
+	if req.ExcludeHidden, err = queryBool(c, "exclude_hidden"); err != nil {
+		return fmt.Errorf("query exclude_hidden: %w", err)
+	}
+
+	clusters, err := g.photo.Geo(c.Request().Context(), req)
+	if err != nil {
+		return fmt.Errorf("geo: %w", err)
+	}
+
+	var collection = featureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]feature, 0, len(clusters)),
+	}
+	for _, cluster := range clusters {
+		f := feature{
+			Type: "Feature",
+			Geometry: geometry{
+				Type:        "Point",
+				Coordinates: [2]float64{cluster.Location.Longitude, cluster.Location.Latitude},
+			},
+			Properties: map[string]any{
+				"count": cluster.Count,
+			},
+		}
+
+		if cluster.Content != nil {
+			f.Properties["id"] = cluster.Content.Original.ID
+			f.Properties["content_type"] = cluster.Content.Original.ContentType
+			f.Properties["captured_at"] = cluster.Content.CapturedAt()
+		} else {
+			f.Properties["cluster"] = true
+			f.BBox = []float64{
+				cluster.Bounds.MinLongitude, cluster.Bounds.MinLatitude,
+				cluster.Bounds.MaxLongitude, cluster.Bounds.MaxLatitude,
+			}
+		}
+
+		collection.Features = append(collection.Features, f)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, mimeGeoJSON)
+
+	return c.JSON(http.StatusOK, collection)
+}
+
+// queryBBox parses `bbox=west,south,east,north`, the whole world by default.
+// West greater than east crosses the antimeridian.
+func queryBBox(c echo.Context) (entity.Bounds, error) {
+	v := c.QueryParam("bbox")
+	if v == "" {
+		return entity.Bounds{
+			MinLatitude:  -90,
+			MinLongitude: -180,
+			MaxLatitude:  90,
+			MaxLongitude: 180,
+		}, nil
+	}
+
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return entity.Bounds{}, fmt.Errorf("bbox `%s` want 4 values: %w", v, entity.ErrInvalidInput)
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return entity.Bounds{}, fmt.Errorf("parse: %w: %w", entity.ErrInvalidInput, err)
+		}
+		values[i] = f
+	}
+
+	if values[1] > values[3] {
+		return entity.Bounds{}, fmt.Errorf("bbox south above north: %w", entity.ErrInvalidInput)
+	}
+
+	return entity.Bounds{
+		MinLongitude: values[0],
+		MinLatitude:  values[1],
+		MaxLongitude: values[2],
+		MaxLatitude:  values[3],
+	}, nil
+}
@@ -2,11 +2,14 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -20,44 +23,115 @@ var (
 )
 
 type Gateway struct {
-	photo   *photo.Photo
-	echo    *echo.Echo
-	address string
+	photo      *photo.Photo
+	echo       *echo.Echo
+	address    string
+	userHeader string
+	origins    []string
+	tus        *tus
+
+	cacheOriginal  string
+	cacheThumbnail string
 }
 
 type GatewayConfig struct {
 	Photo   *photo.Photo
 	Address string
+	// UserHeader names the header the authorization proxy puts the user in.
+	UserHeader string
+	// AllowedOrigins may open WebSockets besides the origin of the host.
+	AllowedOrigins []string
+	// UploadDir stages resumable uploads until they are complete.
+	UploadDir        string
+	UploadExpiration time.Duration
+	UploadMaxSize    int64
+	// CacheControl values for original and thumbnail responses. The
+	// thumbnail value only applies to requests versioned with the hash of the
+	// object, see renditionCache.
+	CacheControlOriginal  string
+	CacheControlThumbnail string
 }
 
 func New(c GatewayConfig) *Gateway {
+	if c.CacheControlOriginal == "" {
+		c.CacheControlOriginal = "private, no-cache"
+	}
+	if c.CacheControlThumbnail == "" {
+		c.CacheControlThumbnail = "private, max-age=31536000, immutable"
+	}
+
 	e := echo.New()
 
 	g := &Gateway{
-		photo:   c.Photo,
-		echo:    e,
-		address: c.Address,
+		photo:      c.Photo,
+		echo:       e,
+		address:    c.Address,
+		userHeader: c.UserHeader,
+		origins:    c.AllowedOrigins,
+		tus:        newTus(c.Photo, c.UploadDir, c.UploadExpiration, c.UploadMaxSize),
+
+		cacheOriginal:  c.CacheControlOriginal,
+		cacheThumbnail: c.CacheControlThumbnail,
 	}
 
+	e.HTTPErrorHandler = errorHandler
 	e.Use(
 		middleware.Recover(),
+		middleware.RequestID(),
 		middleware.Logger(),
+		g.userContext,
 	)
 
 	e.GET("/content", g.hdlrContents)
 	e.GET("/content/:id/original", g.hdlrContentOriginal)
+	e.HEAD("/content/:id/original", g.hdlrContentOriginalHead)
 	e.GET("/content/:id/thumbnail", g.hdlrContentThumbnail)
+	e.HEAD("/content/:id/thumbnail", g.hdlrContentThumbnailHead)
+	e.GET("/content/:id/display", g.hdlrContentDisplay)
+	e.HEAD("/content/:id/display", g.hdlrContentDisplayHead)
+	e.GET("/content/:id/motion", g.hdlrContentMotion)
+	e.GET("/content/:id/sprites.vtt", g.hdlrContentSpritesIndex)
+	e.GET("/content/:id/sprites/:n", g.hdlrContentSpritesSheet)
+	e.GET("/content/:id/hls/:name", g.hdlrContentStream)
+	e.HEAD("/content/:id/motion", g.hdlrContentMotionHead)
+	e.POST("/content", g.hdlrContentBatchUpload)
 	e.POST("/content/:id", g.hdlrContentUpload)
+	e.PATCH("/content/:id", g.hdlrContentUpdate)
 	e.DELETE("/content/:id", g.hdlrContenDelete)
+	e.GET("/changes", g.hdlrChanges)
+	e.GET("/search", g.hdlrSearch)
+	e.GET("/timeline", g.hdlrTimeline)
+	e.GET("/memories", g.hdlrMemories)
+	e.GET("/geo", g.hdlrGeo)
+	e.GET("/archive", g.hdlrArchive)
+	e.POST("/archive", g.hdlrArchive)
+	e.GET("/albums", g.hdlrAlbums)
+	e.POST("/albums", g.hdlrAlbumCreate)
+	e.GET("/albums/:id", g.hdlrAlbum)
+	e.PATCH("/albums/:id", g.hdlrAlbumUpdate)
+	e.DELETE("/albums/:id", g.hdlrAlbumDelete)
+	e.POST("/albums/:id/items", g.hdlrAlbumItemsAdd)
+	e.DELETE("/albums/:id/items", g.hdlrAlbumItemsRemove)
+	e.PUT("/albums/:id/items", g.hdlrAlbumItemsOrder)
+	e.GET("/events", g.hdlrEvents)
+	e.GET("/events/ws", g.hdlrEventsWebSocket)
+	e.GET("/metrics", g.hdlrMetrics)
+	g.tus.register(e)
 
 	return g
 }
 
 func (g *Gateway) Run() error {
+	if err := g.tus.run(); err != nil {
+		return fmt.Errorf("tus run: %w", err)
+	}
+
 	return g.echo.Start(g.address)
 }
 
 func (g *Gateway) Shutdown() error {
+	g.tus.shutdown()
+
 	return g.echo.Shutdown(context.TODO())
 }
 
@@ -70,74 +144,229 @@ func (g *Gateway) hdlrContents(c echo.Context) error {
 	return c.JSON(http.StatusOK, contents)
 }
 
+func (g *Gateway) hdlrSearch(c echo.Context) error {
+	contents, err := g.photo.Search(c.Request().Context(), c.QueryParam("q"))
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, contents)
+}
+
+func (g *Gateway) hdlrTimeline(c echo.Context) error {
+	loc, err := queryLocation(c)
+	if err != nil {
+		return fmt.Errorf("query tz: %w", err)
+	}
+
+	timeline, err := g.photo.Timeline(c.Request().Context(), entity.Granularity(c.QueryParam("granularity")), loc)
+	if err != nil {
+		return fmt.Errorf("timeline: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, timeline)
+}
+
+func (g *Gateway) hdlrMemories(c echo.Context) error {
+	loc, err := queryLocation(c)
+	if err != nil {
+		return fmt.Errorf("query tz: %w", err)
+	}
+
+	var req = entity.MemoriesRequest{
+		Date: time.Now().In(loc),
+	}
+	if v := c.QueryParam("date"); v != "" {
+		if req.Date, err = time.ParseInLocation(time.DateOnly, v, loc); err != nil {
+			return fmt.Errorf("query date: %w: %w", entity.ErrInvalidInput, err)
+		}
+	}
+	if req.ExcludeHidden, err = queryBool(c, "exclude_hidden"); err != nil {
+		return fmt.Errorf("query exclude_hidden: %w", err)
+	}
+	if req.ExcludeScreenshots, err = queryBool(c, "exclude_screenshots"); err != nil {
+		return fmt.Errorf("query exclude_screenshots: %w", err)
+	}
+
+	memories, err := g.photo.Memories(c.Request().Context(), req)
+	if err != nil {
+		return fmt.Errorf("memories: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, memories)
+}
+
 func (g *Gateway) hdlrContentOriginal(c echo.Context) error {
-	ctx := c.Request().Context()
+	return g.serveObject(c, g.photo.ContentOriginal, g.originalCache)
+}
 
-	modifiedSince, err := fromModifiedSince(c.Request().Header.Get("If-Modified-Since"))
-	if err != nil && !errors.Is(err, errEmptyValue) {
-		return fmt.Errorf("modified since: %w", err)
+func (g *Gateway) hdlrContentOriginalHead(c echo.Context) error {
+	return g.serveObjectHead(c, g.photo.ContentOriginalStat, g.originalCache)
+}
+
+func (g *Gateway) hdlrContentThumbnail(c echo.Context) error {
+	read, _, err := g.thumbnailRendition(c)
+	if err != nil {
+		return fmt.Errorf("thumbnail rendition: %w", err)
 	}
 
-	id, err := paramID(c)
+	return g.serveObject(c, read, g.renditionCache)
+}
+
+func (g *Gateway) hdlrContentThumbnailHead(c echo.Context) error {
+	_, stat, err := g.thumbnailRendition(c)
 	if err != nil {
-		return fmt.Errorf("param id: %w", err)
+		return fmt.Errorf("thumbnail rendition: %w", err)
 	}
 
-	var contentRange *string
-	if v := c.Request().Header.Get("Range"); v != "" {
-		contentRange = &v
+	return g.serveObjectHead(c, stat, g.renditionCache)
+}
+
+// thumbnailRendition returns the read and stat functions of the requested
+// thumbnail, `rendition=poster` asks for a still image instead of the
+// animated preview of videos.
+func (g *Gateway) thumbnailRendition(c echo.Context) (objectFunc, objectFunc, error) {
+	switch v := c.QueryParam("rendition"); v {
+	case "":
+		return g.photo.ContentThumbnail, g.photo.ContentThumbnailStat, nil
+	case "poster":
+		return g.photo.ContentPoster, g.photo.ContentPosterStat, nil
+	default:
+		return nil, nil, fmt.Errorf("rendition `%s`: %w", v, entity.ErrInvalidInput)
 	}
+}
 
-	object, err := g.photo.ContentOriginal(ctx, entity.ObjectRequest{
-		ID:              id,
-		IfModifiedSince: modifiedSince,
-		Range:           contentRange,
-	})
+// hdlrContentDisplay serves the original to clients accepting its format and
+// the display rendition to the others.
+func (g *Gateway) hdlrContentDisplay(c echo.Context) error {
+	accept := accepts(c.Request().Header.Get("Accept"))
+	c.Response().Header().Add("Vary", "Accept")
+
+	return g.serveObject(c, func(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+		return g.photo.ContentDisplay(ctx, req, accept)
+	}, g.originalCache)
+}
+
+func (g *Gateway) hdlrContentDisplayHead(c echo.Context) error {
+	accept := accepts(c.Request().Header.Get("Accept"))
+	c.Response().Header().Add("Vary", "Accept")
+
+	return g.serveObjectHead(c, func(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+		return g.photo.ContentDisplayStat(ctx, req, accept)
+	}, g.originalCache)
+}
+
+func (g *Gateway) hdlrContentMotion(c echo.Context) error {
+	return g.serveObject(c, g.photo.ContentMotion, g.originalCache)
+}
+
+func (g *Gateway) hdlrContentMotionHead(c echo.Context) error {
+	return g.serveObjectHead(c, g.photo.ContentMotionStat, g.originalCache)
+}
+
+func (g *Gateway) hdlrContentSpritesIndex(c echo.Context) error {
+	return g.serveObject(c, g.photo.ContentSpritesIndex, g.renditionCache)
+}
+
+func (g *Gateway) hdlrContentSpritesSheet(c echo.Context) error {
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil {
+		return fmt.Errorf("param n: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	return g.serveObject(c, func(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+		return g.photo.ContentSpritesSheet(ctx, req, n)
+	}, g.renditionCache)
+}
+
+// hdlrContentStream serves the HLS stream of a video, the master playlist is
+// the `stream` object of the content and references the other files by name.
+func (g *Gateway) hdlrContentStream(c echo.Context) error {
+	name := c.Param("name")
+
+	return g.serveObject(c, func(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+		return g.photo.ContentStream(ctx, req, name)
+	}, g.originalCache)
+}
+
+type objectFunc func(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error)
+
+// cacheFunc returns the Cache-Control of an object response.
+type cacheFunc func(c echo.Context, object entity.Object) string
+
+func (g *Gateway) originalCache(echo.Context, entity.Object) string {
+	return g.cacheOriginal
+}
+
+// renditionCache returns the thumbnail Cache-Control to requests versioned
+// with the hash of the object, `?v=<hash>`. Rendition URLs do not change on
+// re-upload, unversioned requests revalidate the ETag.
+func (g *Gateway) renditionCache(c echo.Context, object entity.Object) string {
+	if v := c.QueryParam("v"); v != "" && v == object.Hash {
+		return g.cacheThumbnail
+	}
+
+	return "private, no-cache"
+}
+
+// serveObject streams a stored object honoring ranges and conditional headers.
+func (g *Gateway) serveObject(c echo.Context, read objectFunc, cache cacheFunc) error {
+	req, err := objectRequest(c)
 	if err != nil {
-		return toHTTPError(c, err)
+		return fmt.Errorf("object request: %w", err)
+	}
+
+	object, err := read(c.Request().Context(), *req)
+	if errors.Is(err, entity.ErrNotModified) || errors.Is(err, entity.ErrPreconditionFailed) {
+		writeValidators(c, object.Object, cache)
+	}
+	if err != nil {
+		return fmt.Errorf("read object: %w", err)
 	}
 	defer object.Content.Close()
 
+	writeValidators(c, object.Object, cache)
 	c.Response().Header().Set("Accept-Ranges", "bytes")
-	c.Response().Header().Set("Last-Modified", toModifiedSince(object.LastModified))
 	var statusHTTP = http.StatusOK
 	if object.ContentRange != nil {
 		c.Response().Header().Set("Content-Range", *object.ContentRange)
 		statusHTTP = http.StatusPartialContent
 	}
 	if object.ContentLength != nil {
-		c.Response().Header().Set("Content-Length", strconv.Itoa(int(*object.ContentLength)))
+		c.Response().Header().Set("Content-Length", strconv.FormatInt(*object.ContentLength, 10))
 	}
 
 	return c.Stream(statusHTTP, object.ContentType, object.Content)
 }
 
-func (g *Gateway) hdlrContentThumbnail(c echo.Context) error {
-	modifiedSince, err := fromModifiedSince(c.Request().Header.Get("If-Modified-Since"))
-	if err != nil && !errors.Is(err, errEmptyValue) {
-		return fmt.Errorf("modified since: %w", err)
-	}
-
-	id, err := paramID(c)
+func (g *Gateway) serveObjectHead(c echo.Context, stat objectFunc, cache cacheFunc) error {
+	req, err := objectRequest(c)
 	if err != nil {
-		return fmt.Errorf("param id: %w", err)
+		return fmt.Errorf("object request: %w", err)
 	}
 
-	object, err := g.photo.ContentThumbnail(c.Request().Context(), id, modifiedSince)
+	object, err := stat(c.Request().Context(), *req)
+	if errors.Is(err, entity.ErrNotModified) || errors.Is(err, entity.ErrPreconditionFailed) {
+		writeValidators(c, object.Object, cache)
+	}
 	if err != nil {
-		return toHTTPError(c, err)
+		return fmt.Errorf("stat object: %w", err)
 	}
-	defer object.Content.Close()
 
-	c.Response().Header().Set("Last-Modified", toModifiedSince(object.LastModified))
+	writeValidators(c, object.Object, cache)
+	c.Response().Header().Set("Accept-Ranges", "bytes")
+	c.Response().Header().Set("Content-Type", object.ContentType)
+	if object.ContentLength != nil {
+		c.Response().Header().Set("Content-Length", strconv.FormatInt(*object.ContentLength, 10))
+	}
 
-	return c.Stream(http.StatusOK, object.ContentType, object.Content)
+	return c.NoContent(http.StatusOK)
 }
 
 func (g *Gateway) hdlrContentUpload(c echo.Context) error {
 	modifiedSince, err := fromModifiedSince(c.Request().Header.Get("Last-Modified"))
 	if err != nil {
-		return fmt.Errorf("modified since: %w", err)
+		return fmt.Errorf("modified since: %w: %w", entity.ErrInvalidInput, err)
 	}
 
 	defer c.Request().Body.Close()
@@ -161,6 +390,25 @@ func (g *Gateway) hdlrContentUpload(c echo.Context) error {
 	return nil
 }
 
+func (g *Gateway) hdlrContentUpdate(c echo.Context) error {
+	id, err := paramID(c)
+	if err != nil {
+		return fmt.Errorf("param id: %w", err)
+	}
+
+	var update entity.UserMetadataUpdate
+	if err := c.Bind(&update); err != nil {
+		return fmt.Errorf("bind: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	content, err := g.photo.ContentUpdate(c.Request().Context(), id, update)
+	if err != nil {
+		return fmt.Errorf("content update: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, content)
+}
+
 func (g *Gateway) hdlrContenDelete(c echo.Context) error {
 	id, err := paramID(c)
 	if err != nil {
@@ -174,22 +422,183 @@ func (g *Gateway) hdlrContenDelete(c echo.Context) error {
 	return nil
 }
 
+func (g *Gateway) hdlrChanges(c echo.Context) error {
+	since, err := queryInt(c, "since")
+	if err != nil {
+		return fmt.Errorf("query since: %w", err)
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		return fmt.Errorf("query limit: %w", err)
+	}
+
+	changes, err := g.photo.Changes(c.Request().Context(), since, int(limit))
+	if err != nil {
+		return fmt.Errorf("changes: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
+
+// hdlrMetrics serves the counters of the server, not the cmdline and
+// memstats the expvar package publishes for every process.
+func (g *Gateway) hdlrMetrics(c echo.Context) error {
+	metrics := make(map[string]json.RawMessage)
+	expvar.Do(func(kv expvar.KeyValue) {
+		switch kv.Key {
+		case "cmdline", "memstats":
+			return
+		}
+
+		metrics[kv.Key] = json.RawMessage(kv.Value.String())
+	})
+
+	return c.JSON(http.StatusOK, metrics)
+}
+
 func paramID(c echo.Context) (string, error) {
 	v, err := url.QueryUnescape(c.Param("id"))
 	if err != nil {
-		return "", fmt.Errorf("query unescape: %w", err)
+		return "", fmt.Errorf("query unescape: %w: %w", entity.ErrInvalidInput, err)
 	}
 
 	return v, nil
 }
 
+// objectRequest reads the content ID, range and conditional headers.
+func objectRequest(c echo.Context) (*entity.ObjectRequest, error) {
+	id, err := paramID(c)
+	if err != nil {
+		return nil, fmt.Errorf("param id: %w", err)
+	}
+
+	var (
+		header = c.Request().Header
+		req    = entity.ObjectRequest{ID: id}
+	)
+
+	// An invalid date is ignored, RFC 9110 section 13.1.3.
+	req.IfModifiedSince, _ = fromModifiedSince(header.Get("If-Modified-Since"))
+
+	if v := header.Get("If-None-Match"); v != "" {
+		req.IfNoneMatch = &v
+	}
+	if v := header.Get("If-Match"); v != "" {
+		req.IfMatch = &v
+	}
+	if v := header.Get("Range"); v != "" {
+		req.Range = &v
+	}
+
+	if v := header.Get("If-Range"); v != "" {
+		if strings.HasPrefix(v, `"`) || strings.HasPrefix(v, "W/") {
+			req.IfRangeETag = &v
+		} else {
+			// An unparsable date never matches, the full content is served.
+			req.IfRangeModified, err = fromModifiedSince(v)
+			if err != nil {
+				var never int64 = -1
+				req.IfRangeModified = &never
+			}
+		}
+	}
+
+	return &req, nil
+}
+
+func writeValidators(c echo.Context, object entity.Object, cache cacheFunc) {
+	c.Response().Header().Set("ETag", object.ETag())
+	c.Response().Header().Set("Last-Modified", toModifiedSince(object.LastModified))
+	if cacheControl := cache(c, object); cacheControl != "" {
+		c.Response().Header().Set("Cache-Control", cacheControl)
+	}
+}
+
+// queryLocation reads an IANA time zone from the tz parameter, UTC by default.
+func queryLocation(c echo.Context) (*time.Location, error) {
+	v := c.QueryParam("tz")
+	if v == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(v)
+	if err != nil {
+		return nil, fmt.Errorf("load location: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	return loc, nil
+}
+
+// accepts reports whether an Accept header lists a content type explicitly
+// with a non zero quality. Wildcards are ignored, browsers send image/* and
+// */* whether or not they render a format.
+func accepts(header string) func(contentType string) bool {
+	return func(contentType string) bool {
+		for _, v := range strings.Split(header, ",") {
+			mediaType, params, _ := strings.Cut(v, ";")
+			if !strings.EqualFold(strings.TrimSpace(mediaType), contentType) {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(param, "=")
+				if strings.TrimSpace(name) != "q" {
+					continue
+				}
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q == 0 {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		return false
+	}
+}
+
+func queryBool(c echo.Context, name string) (bool, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parse: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	return b, nil
+}
+
+func queryInt(c echo.Context, name string) (int64, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	return i, nil
+}
+
+// fromModifiedSince parses HTTP dates. Clients written against older
+// versions send RFC1123 dates in the zone of the server, such as
+// `Tue, 14 Nov 2023 22:13:20 UTC`, which http.ParseTime rejects.
 func fromModifiedSince(v string) (*int64, error) {
 	if v == "" {
 		return nil, errEmptyValue
 	}
-	t, err := time.Parse(time.RFC1123, v)
+	t, err := http.ParseTime(v)
 	if err != nil {
-		return nil, fmt.Errorf("parse: %w", err)
+		var errRFC1123 error
+		if t, errRFC1123 = time.Parse(time.RFC1123, v); errRFC1123 != nil {
+			return nil, fmt.Errorf("parse: %w", err)
+		}
 	}
 
 	unix := t.Unix()
@@ -198,15 +607,5 @@ func fromModifiedSince(v string) (*int64, error) {
 }
 
 func toModifiedSince(v int64) string {
-	return time.Unix(v, 0).Format(time.RFC1123)
-}
-
-func toHTTPError(c echo.Context, err error) error {
-	switch {
-	case errors.Is(err, entity.ErrNotFound):
-		return echo.ErrNotFound
-	case errors.Is(err, entity.ErrNotModified):
-		return c.NoContent(http.StatusNotModified)
-	}
-	return nil
+	return time.Unix(v, 0).UTC().Format(http.TimeFormat)
 }
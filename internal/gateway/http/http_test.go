@@ -0,0 +1,56 @@
+package http
+
+import "testing"
+
+func TestFromModifiedSince(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		unix  int64
+		ok    bool
+	}{
+		{"Tue, 14 Nov 2023 22:13:20 GMT", 1700000000, true},
+		{"Tuesday, 14-Nov-23 22:13:20 GMT", 1700000000, true},
+		{"Tue Nov 14 22:13:20 2023", 1700000000, true},
+		// Older clients send RFC1123 in the zone of the server.
+		{"Tue, 14 Nov 2023 22:13:20 UTC", 1700000000, true},
+		{"", 0, false},
+		{"1700000000", 0, false},
+		{"2023-11-14T22:13:20Z", 0, false},
+	} {
+		t.Run(tt.value, func(t *testing.T) {
+			unix, err := fromModifiedSince(tt.value)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err %v, want ok %t", err, tt.ok)
+			}
+			if tt.ok && *unix != tt.unix {
+				t.Errorf("unix %d, want %d", *unix, tt.unix)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	for _, tt := range []struct {
+		header      string
+		contentType string
+		ok          bool
+	}{
+		{"", "image/avif", false},
+		{"image/avif", "image/avif", true},
+		{"IMAGE/AVIF", "image/avif", true},
+		{"image/avif,image/webp,*/*;q=0.8", "image/webp", true},
+		{"image/avif;q=0.9, image/webp", "image/avif", true},
+		{"image/avif;q=0", "image/avif", false},
+		{"image/avif; q=0.0", "image/avif", false},
+		// Wildcards do not say a format renders.
+		{"image/*", "image/avif", false},
+		{"*/*", "image/avif", false},
+		{"image/avifs", "image/avif", false},
+	} {
+		t.Run(tt.header+" "+tt.contentType, func(t *testing.T) {
+			if ok := accepts(tt.header)(tt.contentType); ok != tt.ok {
+				t.Errorf("accepts %t, want %t", ok, tt.ok)
+			}
+		})
+	}
+}
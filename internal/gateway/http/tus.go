@@ -0,0 +1,473 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/photo"
+)
+
+// tus 1.0 resumable uploads, see https://tus.io/protocols/resumable-upload.
+
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+	tusInfoExt     = ".info"
+)
+
+var (
+	errTusNotFound = fmt.Errorf("upload: %w", entity.ErrNotFound)
+	errTusExpired  = fmt.Errorf("upload expired: %w", entity.ErrGone)
+)
+
+type tusUpload struct {
+	ID           string `json:"id"`
+	Length       int64  `json:"length"`
+	Offset       int64  `json:"offset"`
+	Metadata     string `json:"metadata"`
+	ContentID    string `json:"content_id"`
+	ContentType  string `json:"content_type"`
+	LastModified int64  `json:"last_modified"`
+	Expires      int64  `json:"expires"`
+}
+
+type tus struct {
+	photo      *photo.Photo
+	dir        string
+	expiration time.Duration
+	maxSize    int64
+
+	locks sync.Map
+	done  chan struct{}
+}
+
+func newTus(p *photo.Photo, dir string, expiration time.Duration, maxSize int64) *tus {
+	if dir == "" {
+		dir = path.Join(os.TempDir(), "photo-uploads")
+	}
+	if expiration <= 0 {
+		expiration = 24 * time.Hour
+	}
+
+	return &tus{
+		photo:      p,
+		dir:        dir,
+		expiration: expiration,
+		maxSize:    maxSize,
+		done:       make(chan struct{}),
+	}
+}
+
+func (t *tus) register(e *echo.Echo) {
+	g := e.Group("/files", t.resumable)
+	g.OPTIONS("", t.hdlrOptions)
+	g.POST("", t.hdlrCreate)
+	g.HEAD("/:uid", t.hdlrOffset)
+	g.PATCH("/:uid", t.hdlrPatch)
+	g.DELETE("/:uid", t.hdlrTerminate)
+}
+
+func (t *tus) run() error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-ticker.C:
+				if err := t.cleanup(); err != nil {
+					fmt.Printf("tus cleanup: %s\n", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (t *tus) shutdown() {
+	close(t.done)
+}
+
+func (t *tus) resumable(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Tus-Resumable", tusVersion)
+
+		if c.Request().Method != http.MethodOptions && c.Request().Header.Get("Tus-Resumable") != tusVersion {
+			c.Response().Header().Set("Tus-Version", tusVersion)
+			return c.NoContent(http.StatusPreconditionFailed)
+		}
+
+		return next(c)
+	}
+}
+
+func (t *tus) hdlrOptions(c echo.Context) error {
+	c.Response().Header().Set("Tus-Version", tusVersion)
+	c.Response().Header().Set("Tus-Extension", tusExtensions)
+	if t.maxSize > 0 {
+		c.Response().Header().Set("Tus-Max-Size", strconv.FormatInt(t.maxSize, 10))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (t *tus) hdlrCreate(c echo.Context) error {
+	length, err := strconv.ParseInt(c.Request().Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return fmt.Errorf("upload length: %w", entity.ErrInvalidInput)
+	}
+	if t.maxSize > 0 && length > t.maxSize {
+		return fmt.Errorf("upload length %d exceeds %d: %w", length, t.maxSize, entity.ErrQuotaExceeded)
+	}
+
+	raw := c.Request().Header.Get("Upload-Metadata")
+	metadata, err := parseTusMetadata(raw)
+	if err != nil {
+		return fmt.Errorf("upload metadata: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	upload := tusUpload{
+		Length:      length,
+		Metadata:    raw,
+		ContentID:   metadata["id"],
+		ContentType: metadata["content_type"],
+		Expires:     time.Now().Add(t.expiration).Unix(),
+	}
+	if upload.ContentID == "" {
+		upload.ContentID = metadata["filename"]
+	}
+	if upload.ContentType == "" {
+		upload.ContentType = metadata["filetype"]
+	}
+	if upload.ContentID == "" || upload.ContentType == "" {
+		return fmt.Errorf("upload metadata id and content_type are required: %w", entity.ErrInvalidInput)
+	}
+	if err := entity.ValidateID(upload.ContentID); err != nil {
+		return fmt.Errorf("upload metadata id: %w", err)
+	}
+	if v := metadata["last_modified"]; v != "" {
+		upload.LastModified, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("upload metadata last_modified: %w: %w", entity.ErrInvalidInput, err)
+		}
+	} else {
+		upload.LastModified = time.Now().Unix()
+	}
+
+	var uid [16]byte
+	if _, err := rand.Read(uid[:]); err != nil {
+		return fmt.Errorf("rand: %w", err)
+	}
+	upload.ID = hex.EncodeToString(uid[:])
+
+	f, err := os.Create(t.dataPath(upload.ID))
+	if err != nil {
+		return fmt.Errorf("create data: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close data: %w", err)
+	}
+
+	if err := t.save(upload); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	// No PATCH follows an empty upload, it is complete once created.
+	if upload.Length == 0 {
+		if err := t.complete(c.Request().Context(), &upload); err != nil {
+			return fmt.Errorf("complete: %w", err)
+		}
+	}
+
+	c.Response().Header().Set("Location", path.Join(c.Path(), upload.ID))
+	c.Response().Header().Set("Upload-Expires", toModifiedSince(upload.Expires))
+
+	return c.NoContent(http.StatusCreated)
+}
+
+func (t *tus) hdlrOffset(c echo.Context) error {
+	unlock, err := t.acquire(c.Param("uid"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	upload, err := t.load(c.Param("uid"))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Response().Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Response().Header().Set("Upload-Expires", toModifiedSince(upload.Expires))
+	if upload.Metadata != "" {
+		c.Response().Header().Set("Upload-Metadata", upload.Metadata)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (t *tus) hdlrPatch(c echo.Context) error {
+	if c.Request().Header.Get("Content-Type") != tusContentType {
+		return fmt.Errorf("expected %s: %w", tusContentType, entity.ErrUnsupportedMedia)
+	}
+
+	offset, err := strconv.ParseInt(c.Request().Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("upload offset: %w: %w", entity.ErrInvalidInput, err)
+	}
+
+	defer c.Request().Body.Close()
+
+	unlock, err := t.acquire(c.Param("uid"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	upload, err := t.load(c.Param("uid"))
+	if err != nil {
+		return err
+	}
+
+	if offset != upload.Offset {
+		return fmt.Errorf("upload offset %d, expected %d: %w", offset, upload.Offset, entity.ErrConflict)
+	}
+
+	f, err := os.OpenFile(t.dataPath(upload.ID), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("open data: %w", err)
+	}
+
+	// A dropped connection still keeps everything received so far.
+	n, copyErr := io.Copy(f, io.LimitReader(c.Request().Body, upload.Length-upload.Offset))
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close data: %w", err)
+	}
+
+	upload.Offset += n
+	upload.Expires = time.Now().Add(t.expiration).Unix()
+	if err := t.save(*upload); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	if copyErr != nil {
+		return fmt.Errorf("copy chunk: %w", copyErr)
+	}
+
+	if upload.Offset == upload.Length {
+		if err := t.complete(c.Request().Context(), upload); err != nil {
+			return fmt.Errorf("complete: %w", err)
+		}
+	}
+
+	c.Response().Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Response().Header().Set("Upload-Expires", toModifiedSince(upload.Expires))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (t *tus) hdlrTerminate(c echo.Context) error {
+	unlock, err := t.acquire(c.Param("uid"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := t.load(c.Param("uid")); err != nil {
+		return err
+	}
+
+	if err := t.remove(c.Param("uid")); err != nil {
+		return fmt.Errorf("remove: %w", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// complete hands the staged file over to the catalog. On failure the staged
+// file is kept, so a PATCH with an empty body at the final offset retries.
+func (t *tus) complete(ctx context.Context, upload *tusUpload) error {
+	f, err := os.Open(t.dataPath(upload.ID))
+	if err != nil {
+		return fmt.Errorf("open data: %w", err)
+	}
+	defer f.Close()
+
+	if err := t.photo.ContentUpload(ctx, entity.ObjectReader{
+		Object: entity.Object{
+			ID:           upload.ContentID,
+			ContentType:  upload.ContentType,
+			LastModified: upload.LastModified,
+		},
+		Content: f,
+	}); err != nil {
+		return fmt.Errorf("content upload: %w", err)
+	}
+
+	if err := t.remove(upload.ID); err != nil {
+		return fmt.Errorf("remove: %w", err)
+	}
+
+	return nil
+}
+
+func (t *tus) cleanup() error {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return fmt.Errorf("read dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		uid, ok := strings.CutSuffix(entry.Name(), tusInfoExt)
+		if !ok {
+			continue
+		}
+
+		unlock := t.lock(uid)
+		_, err := t.load(uid)
+		if errors.Is(err, errTusExpired) {
+			err = t.remove(uid)
+		}
+		unlock()
+
+		if err != nil && !errors.Is(err, errTusNotFound) {
+			return fmt.Errorf("upload `%s`: %w", uid, err)
+		}
+	}
+
+	return nil
+}
+
+// acquire locks an existing upload. Unknown IDs are rejected first, a lock
+// is only dropped by remove and would be kept for every made up ID.
+func (t *tus) acquire(uid string) (func(), error) {
+	if !isTusID(uid) {
+		return nil, errTusNotFound
+	}
+	if _, err := os.Stat(t.infoPath(uid)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errTusNotFound
+		}
+		return nil, fmt.Errorf("stat info: %w", err)
+	}
+
+	return t.lock(uid), nil
+}
+
+func (t *tus) lock(uid string) func() {
+	v, _ := t.locks.LoadOrStore(uid, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	return mu.Unlock
+}
+
+func (t *tus) load(uid string) (*tusUpload, error) {
+	if !isTusID(uid) {
+		return nil, errTusNotFound
+	}
+
+	data, err := os.ReadFile(t.infoPath(uid))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errTusNotFound
+		}
+		return nil, fmt.Errorf("read info: %w", err)
+	}
+
+	var upload tusUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("unmarshal info: %w", err)
+	}
+
+	if upload.Expires < time.Now().Unix() {
+		return nil, errTusExpired
+	}
+
+	return &upload, nil
+}
+
+func (t *tus) save(upload tusUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("marshal info: %w", err)
+	}
+
+	if err := os.WriteFile(t.infoPath(upload.ID), data, 0o644); err != nil {
+		return fmt.Errorf("write info: %w", err)
+	}
+
+	return nil
+}
+
+func (t *tus) remove(uid string) error {
+	if err := os.Remove(t.dataPath(uid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove data: %w", err)
+	}
+	if err := os.Remove(t.infoPath(uid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove info: %w", err)
+	}
+	t.locks.Delete(uid)
+
+	return nil
+}
+
+func (t *tus) dataPath(uid string) string {
+	return path.Join(t.dir, uid)
+}
+
+func (t *tus) infoPath(uid string) string {
+	return path.Join(t.dir, uid+tusInfoExt)
+}
+
+func isTusID(uid string) bool {
+	_, err := hex.DecodeString(uid)
+
+	return err == nil && len(uid) == 32
+}
+
+func parseTusMetadata(v string) (map[string]string, error) {
+	var metadata = make(map[string]string)
+	if v == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(v, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("empty key")
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("decode `%s`: %w", key, err)
+		}
+		metadata[key] = string(decoded)
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		value    string
+		metadata map[string]string
+		ok       bool
+	}{
+		{"empty", "", map[string]string{}, true},
+		{"pair", "filename YS5qcGc=", map[string]string{"filename": "a.jpg"}, true},
+		{"pairs", "filename YS5qcGc=, filetype aW1hZ2UvanBlZw==", map[string]string{"filename": "a.jpg", "filetype": "image/jpeg"}, true},
+		{"key only", "is_confidential", map[string]string{"is_confidential": ""}, true},
+		{"empty key", "filename YS5qcGc=, ", nil, false},
+		{"not base64", "filename a.jpg", nil, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := parseTusMetadata(tt.value)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err %v, want ok %t", err, tt.ok)
+			}
+			if tt.ok && !maps.Equal(metadata, tt.metadata) {
+				t.Errorf("metadata %v, want %v", metadata, tt.metadata)
+			}
+		})
+	}
+}
+
+func newTestTus(t *testing.T) (*echo.Echo, *tus) {
+	t.Helper()
+
+	e := echo.New()
+	e.HTTPErrorHandler = errorHandler
+
+	// Uploads in the tests never complete, there is no catalog to hand them to.
+	tu := newTus(nil, t.TempDir(), time.Hour, 0)
+	tu.register(e)
+
+	return e, tu
+}
+
+func tusRequest(e *echo.Echo, method, target string, header map[string]string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestTusOffsets(t *testing.T) {
+	e, tu := newTestTus(t)
+
+	rec := tusRequest(e, http.MethodPost, "/files", map[string]string{
+		"Upload-Length":   "10",
+		"Upload-Metadata": "filename YS5qcGc=, filetype aW1hZ2UvanBlZw==",
+	}, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status %d, want %d", rec.Code, http.StatusCreated)
+	}
+	location := rec.Header().Get("Location")
+
+	patch := func(offset string) map[string]string {
+		return map[string]string{"Content-Type": tusContentType, "Upload-Offset": offset}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		target string
+		header map[string]string
+		body   string
+		status int
+		offset string
+	}{
+		{"first chunk", http.MethodPatch, location, patch("0"), "abcd", http.StatusNoContent, "4"},
+		{"repeated chunk", http.MethodPatch, location, patch("0"), "abcd", http.StatusConflict, ""},
+		{"offset ahead", http.MethodPatch, location, patch("6"), "gh", http.StatusConflict, ""},
+		{"offset not a number", http.MethodPatch, location, patch("four"), "ef", http.StatusBadRequest, ""},
+		{"content type", http.MethodPatch, location, map[string]string{"Upload-Offset": "4"}, "ef", http.StatusUnsupportedMediaType, ""},
+		{"head", http.MethodHead, location, nil, "", http.StatusOK, "4"},
+		{"second chunk", http.MethodPatch, location, patch("4"), "ef", http.StatusNoContent, "6"},
+		{"unknown upload", http.MethodPatch, "/files/0123456789abcdef0123456789abcdef", patch("0"), "ab", http.StatusNotFound, ""},
+		{"not an upload id", http.MethodHead, "/files/..%2Fcontent.json", nil, "", http.StatusNotFound, ""},
+		{"no tus version", http.MethodHead, location, map[string]string{"Tus-Resumable": ""}, "", http.StatusPreconditionFailed, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tusRequest(e, tt.method, tt.target, tt.header, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Upload-Offset"); got != tt.offset {
+				t.Errorf("offset %q, want %q", got, tt.offset)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(tu.dataPath(path.Base(location)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "abcdef" {
+		t.Errorf("data %q, want %q", data, "abcdef")
+	}
+
+	if rec := tusRequest(e, http.MethodDelete, location, nil, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("terminate status %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := tusRequest(e, http.MethodHead, location, nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("head after terminate status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestTusExpired(t *testing.T) {
+	e, tu := newTestTus(t)
+
+	upload := tusUpload{ID: "0123456789abcdef0123456789abcdef", Length: 10, Expires: time.Now().Add(-time.Minute).Unix()}
+	if err := tu.save(upload); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := tusRequest(e, http.MethodHead, "/files/"+upload.ID, nil, ""); rec.Code != http.StatusGone {
+		t.Errorf("status %d, want %d", rec.Code, http.StatusGone)
+	}
+
+	if err := tu.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tu.infoPath(upload.ID)); !os.IsNotExist(err) {
+		t.Errorf("info after cleanup: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package photo
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const AlbumsName = "albums.json"
+
+func (p *Photo) Albums(ctx context.Context) ([]entity.Album, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var albums = make([]entity.Album, 0, len(p.albums))
+	for _, album := range p.albums {
+		albums = append(albums, p.albumEvaluate(album, entity.ViewerFromContext(ctx)))
+	}
+
+	return albums, nil
+}
+
+// Album returns the album with its contents in album order.
+func (p *Photo) Album(ctx context.Context, id string) (*entity.AlbumContents, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	idx, err := p.albumIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	album := p.albumEvaluate(p.albums[idx], entity.ViewerFromContext(ctx))
+
+	var contents = make([]entity.Content, 0, len(album.Items))
+	for _, item := range album.Items {
+		if i := p.contentIndex(item); i != -1 {
+			contents = append(contents, p.contents[i].VisibleTo(entity.ViewerFromContext(ctx)))
+		}
+	}
+
+	return &entity.AlbumContents{
+		Album:    album,
+		Contents: contents,
+	}, nil
+}
+
+// AlbumCreate creates a manual album, or a smart album when query is set.
+func (p *Photo) AlbumCreate(ctx context.Context, name string, query *entity.Query) (*entity.Album, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("album name: %w", entity.ErrInvalidInput)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("new id: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now().Unix()
+	album := entity.Album{
+		ID:       id,
+		Name:     name,
+		Items:    make([]string, 0),
+		Query:    query,
+		Created:  now,
+		Modified: now,
+	}
+	p.albums = append(p.albums, album)
+
+	if err := p.albumsUpload(ctx); err != nil {
+		return nil, fmt.Errorf("albums upload: %w", err)
+	}
+
+	album = p.albumEvaluate(album, entity.ViewerFromContext(ctx))
+
+	return &album, nil
+}
+
+// AlbumUpdate renames the album and selects its cover. The cover must be an
+// item of the album, an empty cover falls back to the first item.
+func (p *Photo) AlbumUpdate(ctx context.Context, id string, update entity.AlbumUpdate) (*entity.Album, error) {
+	return p.albumModify(ctx, id, func(album *entity.Album) error {
+		if update.Name != nil {
+			name := strings.TrimSpace(*update.Name)
+			if name == "" {
+				return fmt.Errorf("album name: %w", entity.ErrInvalidInput)
+			}
+			album.Name = name
+		}
+
+		if update.Query != nil {
+			if album.Query == nil {
+				return fmt.Errorf("manual album can not become smart: %w", entity.ErrConflict)
+			}
+			album.Query = update.Query
+		}
+
+		if update.Cover != nil {
+			if *update.Cover != "" && !slices.Contains(p.albumEvaluate(*album, entity.ViewerFromContext(ctx)).Items, *update.Cover) {
+				return fmt.Errorf("cover `%s` is not in album: %w", *update.Cover, entity.ErrInvalidInput)
+			}
+			album.Cover = *update.Cover
+		}
+
+		return nil
+	})
+}
+
+func (p *Photo) AlbumDelete(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx, err := p.albumIndex(id)
+	if err != nil {
+		return err
+	}
+
+	p.albums = slices.Delete(p.albums, idx, idx+1)
+
+	if err := p.albumsUpload(ctx); err != nil {
+		return fmt.Errorf("albums upload: %w", err)
+	}
+
+	return nil
+}
+
+// AlbumAdd appends contents to the album, skipping ones already in it.
+func (p *Photo) AlbumAdd(ctx context.Context, id string, items []string) (*entity.Album, error) {
+	return p.albumModify(ctx, id, func(album *entity.Album) error {
+		if album.Query != nil {
+			return fmt.Errorf("smart album items: %w", entity.ErrConflict)
+		}
+
+		for _, item := range items {
+			if p.contentIndex(item) == -1 {
+				return fmt.Errorf("content `%s`: %w", item, entity.ErrNotFound)
+			}
+			if !slices.Contains(album.Items, item) {
+				album.Items = append(album.Items, item)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (p *Photo) AlbumRemove(ctx context.Context, id string, items []string) (*entity.Album, error) {
+	return p.albumModify(ctx, id, func(album *entity.Album) error {
+		if album.Query != nil {
+			return fmt.Errorf("smart album items: %w", entity.ErrConflict)
+		}
+
+		albumRemove(album, items...)
+
+		return nil
+	})
+}
+
+// AlbumOrder sets the manual order, items must be a permutation of the album.
+func (p *Photo) AlbumOrder(ctx context.Context, id string, items []string) (*entity.Album, error) {
+	return p.albumModify(ctx, id, func(album *entity.Album) error {
+		if album.Query != nil {
+			return fmt.Errorf("smart album items: %w", entity.ErrConflict)
+		}
+
+		sorted, current := slices.Clone(items), slices.Clone(album.Items)
+		slices.Sort(sorted)
+		slices.Sort(current)
+		if !slices.Equal(sorted, current) {
+			return fmt.Errorf("order does not match album items: %w", entity.ErrInvalidInput)
+		}
+
+		album.Items = slices.Clone(items)
+
+		return nil
+	})
+}
+
+func (p *Photo) albumModify(ctx context.Context, id string, modify func(album *entity.Album) error) (*entity.Album, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx, err := p.albumIndex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	album := p.albums[idx]
+	album.Items = slices.Clone(album.Items)
+	if err := modify(&album); err != nil {
+		return nil, err
+	}
+	album.Modified = time.Now().Unix()
+	p.albums[idx] = album
+
+	if err := p.albumsUpload(ctx); err != nil {
+		return nil, fmt.Errorf("albums upload: %w", err)
+	}
+
+	album = p.albumEvaluate(album, entity.ViewerFromContext(ctx))
+
+	return &album, nil
+}
+
+// albumsForget drops deleted contents from every album and reports whether
+// any album changed. Must be called with the write lock held.
+func (p *Photo) albumsForget(id string) bool {
+	var changed bool
+	for i := range p.albums {
+		if slices.Contains(p.albums[i].Items, id) || p.albums[i].Cover == id {
+			albumRemove(&p.albums[i], id)
+			p.albums[i].Modified = time.Now().Unix()
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// albumEvaluate fills items of smart albums from the catalog, ordered by
+// capture time, so they follow uploads without being stored. Queries only
+// see the locations visible to the viewer. An empty cover falls back to the
+// first item.
+func (p *Photo) albumEvaluate(album entity.Album, v entity.Viewer) entity.Album {
+	if album.Query == nil {
+		return albumCover(album)
+	}
+
+	var contents = make([]entity.Content, 0)
+	for _, c := range p.contents {
+		if album.Query.Match(c.VisibleTo(v)) {
+			contents = append(contents, c)
+		}
+	}
+	slices.SortStableFunc(contents, func(a, b entity.Content) int { return cmp.Compare(a.CapturedAt(), b.CapturedAt()) })
+
+	album.Items = make([]string, 0, len(contents))
+	for _, c := range contents {
+		album.Items = append(album.Items, c.Original.ID)
+	}
+
+	return albumCover(album)
+}
+
+func albumCover(album entity.Album) entity.Album {
+	if album.Cover == "" && len(album.Items) != 0 {
+		album.Cover = album.Items[0]
+	}
+
+	return album
+}
+
+func albumRemove(album *entity.Album, items ...string) {
+	album.Items = slices.DeleteFunc(album.Items, func(item string) bool { return slices.Contains(items, item) })
+	if slices.Contains(items, album.Cover) {
+		album.Cover = ""
+	}
+}
+
+func (p *Photo) albumIndex(id string) (int, error) {
+	idx := slices.IndexFunc(p.albums, func(a entity.Album) bool { return a.ID == id })
+	if idx == -1 {
+		return -1, fmt.Errorf("search album: %w", entity.ErrNotFound)
+	}
+
+	return idx, nil
+}
+
+func (p *Photo) albumsUpload(ctx context.Context) error {
+	return upload(ctx, p.storage, AlbumsName, p.albums)
+}
+
+func newID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("rand: %w", err)
+	}
+
+	return hex.EncodeToString(id[:]), nil
+}
@@ -0,0 +1,152 @@
+package photo
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+const archiveTimeLayout = "2006-01-02_15-04-05"
+
+// Select returns contents matching the selection ordered by capture time.
+// Originals are archived as stored, with their GPS metadata, so contents whose
+// location is private to the viewer are left out.
+func (p *Photo) Select(ctx context.Context, sel entity.Selection) ([]entity.Content, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var (
+		viewer   = entity.ViewerFromContext(ctx)
+		contents = make([]entity.Content, 0)
+	)
+	for _, c := range p.contents {
+		if c.LocationPrivate && !viewer.Owns(c) {
+			continue
+		}
+		if sel.Match(c) {
+			contents = append(contents, c)
+		}
+	}
+
+	slices.SortStableFunc(contents, func(a, b entity.Content) int { return cmp.Compare(a.CapturedAt(), b.CapturedAt()) })
+
+	return contents, nil
+}
+
+// Archive writes the originals of contents as a ZIP stream. Media is already
+// compressed, so entries are stored; sizes over 4 GiB switch to ZIP64.
+func (p *Photo) Archive(ctx context.Context, contents []entity.Content, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	names := archiveNames(contents)
+	for i, c := range contents {
+		if err := p.archiveEntry(ctx, zw, c, names[i]); err != nil {
+			return fmt.Errorf("entry `%s`: %w", c.Original.ID, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Photo) archiveEntry(ctx context.Context, zw *zip.Writer, c entity.Content, name string) error {
+	_, objectPath := originalRendition(c)
+
+	object, err := p.storage.Download(ctx, repository.ObjectRequest{
+		Path: objectPath,
+	})
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	defer object.Content.Close()
+
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: time.Unix(c.CapturedAt(), 0).UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("create header: %w", err)
+	}
+
+	if _, err := io.Copy(fw, object.Content); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	return nil
+}
+
+// archiveNames names the entries of contents, a name already taken gets a
+// counter before the extension.
+func archiveNames(contents []entity.Content) []string {
+	// taken holds every emitted name, a generated name may be the name of
+	// another content.
+	var (
+		names = make([]string, 0, len(contents))
+		taken = make(map[string]struct{})
+	)
+	for _, c := range contents {
+		name := archiveName(c)
+		base, ext := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+		for n := 1; ; n++ {
+			if _, ok := taken[name]; !ok {
+				break
+			}
+			name = fmt.Sprintf("%s_%d%s", base, n, ext)
+		}
+		taken[name] = struct{}{}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// archiveName keeps the original file name when the ID looks like one,
+// otherwise names the file after its capture time. Names never hold a path,
+// entries can not be extracted outside of the target directory.
+func archiveName(c entity.Content) string {
+	name := path.Base(strings.ReplaceAll(c.Original.ID, "\\", "/"))
+	if path.Ext(name) != "" && name != "." && name != "/" && !strings.Contains(name, "..") {
+		return name
+	}
+
+	return time.Unix(c.CapturedAt(), 0).UTC().Format(archiveTimeLayout) + extension(c.Original.ContentType)
+}
+
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/heic":      ".heic",
+	"image/heif":      ".heif",
+	"image/tiff":      ".tiff",
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+}
+
+func extension(contentType string) string {
+	if ext, ok := extensions[contentType]; ok {
+		return ext
+	}
+
+	if ext, ok := entity.RawExtension(contentType); ok {
+		return ext
+	}
+
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) != 0 {
+		return exts[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,63 @@
+package photo
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+func archived(id, contentType string, lastModified int64) entity.Content {
+	return entity.Content{
+		Original: entity.Object{ID: id, ContentType: contentType, LastModified: lastModified},
+	}
+}
+
+func TestArchiveNames(t *testing.T) {
+	// 2023-11-14 22:13:20 UTC.
+	const captured = 1700000000
+
+	for _, tt := range []struct {
+		name     string
+		contents []entity.Content
+		want     []string
+	}{
+		{
+			"original names",
+			[]entity.Content{archived("a.jpg", "image/jpeg", captured), archived("b.mov", "video/quicktime", captured)},
+			[]string{"a.jpg", "b.mov"},
+		},
+		{
+			"capture time without extension",
+			[]entity.Content{archived("IMG0001", "image/heic", captured), archived("IMG0002", "video/mp4", captured+1)},
+			[]string{"2023-11-14_22-13-20.heic", "2023-11-14_22-13-21.mp4"},
+		},
+		{
+			"same capture time",
+			[]entity.Content{archived("IMG0001", "image/jpeg", captured), archived("IMG0002", "image/jpeg", captured), archived("IMG0003", "image/jpeg", captured)},
+			[]string{"2023-11-14_22-13-20.jpg", "2023-11-14_22-13-20_1.jpg", "2023-11-14_22-13-20_2.jpg"},
+		},
+		{
+			"same base name",
+			[]entity.Content{archived("a.jpg", "image/jpeg", captured), archived(`old\a.jpg`, "image/jpeg", captured)},
+			[]string{"a.jpg", "a_1.jpg"},
+		},
+		{
+			// The counter name of one content is the original name of the next.
+			"counter taken",
+			[]entity.Content{archived("a.jpg", "image/jpeg", captured), archived(`old\a.jpg`, "image/jpeg", captured), archived("a_1.jpg", "image/jpeg", captured)},
+			[]string{"a.jpg", "a_1.jpg", "a_1_1.jpg"},
+		},
+		{
+			"no paths",
+			[]entity.Content{archived(`..\..\evil.jpg`, "image/jpeg", captured), archived("..", "image/jpeg", captured)},
+			[]string{"evil.jpg", "2023-11-14_22-13-20.jpg"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := archiveNames(tt.contents); !slices.Equal(got, tt.want) {
+				t.Errorf("names %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
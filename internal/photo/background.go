@@ -0,0 +1,218 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+// task creates a rendition too slow for the upload request in the
+// background.
+type task struct {
+	kind  string
+	event entity.EventType
+	// applies reports whether the task creates a rendition of the original.
+	applies func(original entity.Object) bool
+	// done reports whether the content has the rendition already.
+	done func(c entity.Content) bool
+	// create runs the tools on the staged original, writing to dir. A nil
+	// store means there is nothing to store.
+	create func(ctx context.Context, file string, original entity.Object, dir string) (store, error)
+}
+
+// store uploads the created files and returns the update of the content.
+type store func(ctx context.Context) (func(c *entity.Content), error)
+
+// queue holds the contents waiting for background renditions. IDs are
+// deduplicated so that a content uploaded twice is processed once.
+type queue struct {
+	mu      sync.Mutex
+	pending map[string]struct{}
+	wake    chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{
+		pending: make(map[string]struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (q *queue) push(id string) {
+	q.mu.Lock()
+	q.pending[id] = struct{}{}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *queue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id := range q.pending {
+		delete(q.pending, id)
+		return id, true
+	}
+
+	return "", false
+}
+
+// work runs in the background, one content at a time as transcoding takes
+// every core it gets.
+func (p *Photo) work(ctx context.Context) {
+	for range p.queue.wake {
+		for {
+			id, ok := p.queue.pop()
+			if !ok {
+				break
+			}
+
+			if err := p.process(ctx, id); err != nil {
+				fmt.Printf("Process `%s`: %s\n", id, err)
+			}
+		}
+	}
+}
+
+// enqueue queues the content when a task is left to do.
+func (p *Photo) enqueue(c entity.Content) {
+	for _, t := range p.tasks {
+		if t.applies(c.Original) && !t.done(c) {
+			p.queue.push(c.Original.ID)
+			return
+		}
+	}
+}
+
+// backfill queues contents stored without their background renditions.
+func (p *Photo) backfill() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, c := range p.contents {
+		p.enqueue(c)
+	}
+}
+
+func (p *Photo) process(ctx context.Context, id string) error {
+	p.mu.RLock()
+	idx := p.contentIndex(id)
+	var content entity.Content
+	if idx != -1 {
+		content = p.contents[idx]
+	}
+	p.mu.RUnlock()
+
+	if idx == -1 {
+		return nil
+	}
+
+	tmp, err := os.MkdirTemp("", "process-*")
+	if err != nil {
+		return fmt.Errorf("mkdir temp: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var file string
+	for _, t := range p.tasks {
+		if !t.applies(content.Original) || t.done(content) {
+			continue
+		}
+
+		if file == "" {
+			if file, err = p.downloadFile(ctx, path.Join(OriginalsPath, id), tmp); err != nil {
+				return fmt.Errorf("download original: %w", err)
+			}
+		}
+
+		if err := p.run(ctx, t, file, content.Original, tmp); err != nil {
+			fmt.Printf("Create %s `%s`: %s\n", t.kind, id, err)
+		}
+	}
+
+	return nil
+}
+
+// run creates the rendition of one task and stores it unless the content was
+// deleted or re-uploaded meanwhile, the re-upload is queued again.
+func (p *Photo) run(ctx context.Context, t task, file string, original entity.Object, tmp string) error {
+	dir, err := os.MkdirTemp(tmp, t.kind+"-*")
+	if err != nil {
+		return fmt.Errorf("mkdir temp: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := t.create(ctx, file, original, dir)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if s == nil {
+		return nil
+	}
+
+	unlock := p.locks.lock(original.ID)
+	defer unlock()
+
+	p.mu.RLock()
+	idx := p.contentIndex(original.ID)
+	current := idx != -1 && p.contents[idx].Original.Hash == original.Hash
+	p.mu.RUnlock()
+
+	if !current {
+		return nil
+	}
+
+	apply, err := s(ctx)
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx = p.contentIndex(original.ID)
+	apply(&p.contents[idx])
+	p.contents[idx].Seq = p.nextSeq()
+	content := p.contents[idx]
+
+	if err := p.contentsUpload(ctx); err != nil {
+		return fmt.Errorf("contents upload: %w", err)
+	}
+
+	p.publish(t.event, original.ID, content.Owner, &content)
+
+	return nil
+}
+
+// downloadFile copies a stored object into dir.
+func (p *Photo) downloadFile(ctx context.Context, objectPath, dir string) (string, error) {
+	r, err := p.storage.Download(ctx, repository.ObjectRequest{
+		Path: objectPath,
+	})
+	if err != nil {
+		return "", fmt.Errorf("download: %w", err)
+	}
+	defer r.Content.Close()
+
+	f, err := os.Create(path.Join(dir, "original"+path.Ext(objectPath)))
+	if err != nil {
+		return "", fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r.Content); err != nil {
+		return "", fmt.Errorf("copy: %w", err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,142 @@
+package photo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const HorizonName = "horizon.json"
+
+// tombstonesRetention is how long deletes stay in the delta feed, clients
+// that have not synced for longer have to resync in full.
+const tombstonesRetention = 90 * 24 * time.Hour
+
+// horizon is the newest sequence of a compacted tombstone. Cursors before it
+// may have missed deletes.
+type horizon struct {
+	Seq int64 `json:"seq"`
+}
+
+// Changes returns upserts and deletes recorded after the since cursor, ordered
+// by sequence. A limit <= 0 returns the whole delta. A cursor older than the
+// retained tombstones fails with entity.ErrResyncRequired.
+func (p *Photo) Changes(ctx context.Context, since int64, limit int) (*entity.Changes, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if since > 0 && since < p.horizon.Seq {
+		return nil, fmt.Errorf("cursor %d before %d: %w", since, p.horizon.Seq, entity.ErrResyncRequired)
+	}
+
+	var (
+		viewer  = entity.ViewerFromContext(ctx)
+		changes = make([]entity.Change, 0)
+	)
+	for _, c := range p.contents {
+		if c.Seq <= since {
+			continue
+		}
+
+		content := c.VisibleTo(viewer)
+		changes = append(changes, entity.Change{
+			Seq:     c.Seq,
+			Type:    entity.ChangeUpsert,
+			ID:      c.Original.ID,
+			Content: &content,
+		})
+	}
+	for _, t := range p.tombstones {
+		if t.Seq <= since {
+			continue
+		}
+
+		changes = append(changes, entity.Change{
+			Seq:  t.Seq,
+			Type: entity.ChangeDelete,
+			ID:   t.ID,
+		})
+	}
+
+	slices.SortFunc(changes, func(a, b entity.Change) int { return cmp.Compare(a.Seq, b.Seq) })
+
+	if limit > 0 && len(changes) > limit {
+		changes = changes[:limit]
+
+		return &entity.Changes{
+			Changes: changes,
+			Cursor:  changes[len(changes)-1].Seq,
+			More:    true,
+		}, nil
+	}
+
+	return &entity.Changes{
+		Changes: changes,
+		Cursor:  p.seq,
+	}, nil
+}
+
+// nextSeq must be called with the write lock held.
+func (p *Photo) nextSeq() int64 {
+	p.seq++
+
+	return p.seq
+}
+
+// restoreSeq resumes the sequence from the catalog and numbers contents
+// uploaded before the delta feed existed.
+func (p *Photo) restoreSeq() {
+	p.seq = p.horizon.Seq
+	for _, c := range p.contents {
+		p.seq = max(p.seq, c.Seq)
+	}
+	for _, t := range p.tombstones {
+		p.seq = max(p.seq, t.Seq)
+	}
+
+	for i := range p.contents {
+		if p.contents[i].Seq == 0 {
+			p.contents[i].Seq = p.nextSeq()
+		}
+	}
+}
+
+// compactTombstones drops tombstones past retention and reports whether any
+// were dropped. It must be called with the write lock held.
+func (p *Photo) compactTombstones(now time.Time) bool {
+	expired := now.Add(-tombstonesRetention).Unix()
+
+	n := len(p.tombstones)
+	p.tombstones = slices.DeleteFunc(p.tombstones, func(t entity.Tombstone) bool {
+		if t.Deleted >= expired {
+			return false
+		}
+		p.horizon.Seq = max(p.horizon.Seq, t.Seq)
+
+		return true
+	})
+
+	return len(p.tombstones) != n
+}
+
+// tombstonesCompact drops tombstones past retention and stores the horizon
+// before the tombstones, so that a failure in between never loses deletes.
+func (p *Photo) tombstonesCompact(ctx context.Context) error {
+	if !p.compactTombstones(time.Now()) {
+		return nil
+	}
+
+	if err := upload(ctx, p.storage, HorizonName, p.horizon); err != nil {
+		return fmt.Errorf("horizon upload: %w", err)
+	}
+
+	if err := p.tombstonesUpload(ctx); err != nil {
+		return fmt.Errorf("tombstones upload: %w", err)
+	}
+
+	return nil
+}
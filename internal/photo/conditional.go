@@ -0,0 +1,65 @@
+package photo
+
+import (
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// preconditions evaluates conditional request headers in the order of
+// RFC 9110 section 13.2.2. A failed If-Range drops the range so the whole
+// object is served.
+func preconditions(object entity.Object, req *entity.ObjectRequest) error {
+	etag := object.ETag()
+
+	if req.IfMatch != nil && !matchETag(*req.IfMatch, etag, true) {
+		return entity.ErrPreconditionFailed
+	}
+
+	if req.IfNoneMatch != nil {
+		if matchETag(*req.IfNoneMatch, etag, false) {
+			return entity.ErrNotModified
+		}
+	} else if req.IfModifiedSince != nil && object.LastModified <= *req.IfModifiedSince {
+		return entity.ErrNotModified
+	}
+
+	if req.Range != nil {
+		switch {
+		case req.IfRangeETag != nil:
+			if !strongEqual(*req.IfRangeETag, etag) {
+				req.Range = nil
+			}
+		case req.IfRangeModified != nil:
+			if object.LastModified != *req.IfRangeModified {
+				req.Range = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchETag reports whether any tag of an If-Match/If-None-Match list matches.
+func matchETag(list, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if strong && strongEqual(tag, etag) {
+			return true
+		}
+		if !strong && strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func strongEqual(a, b string) bool {
+	return a == b && !strings.HasPrefix(a, "W/")
+}
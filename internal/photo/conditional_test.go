@@ -0,0 +1,59 @@
+package photo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPreconditions(t *testing.T) {
+	var (
+		strong = entity.Object{ID: "a.jpg", Hash: "abc", LastModified: 100}
+		weak   = entity.Object{ID: "a.jpg", LastModified: 100}
+	)
+
+	for _, tt := range []struct {
+		name   string
+		object entity.Object
+		req    entity.ObjectRequest
+		err    error
+		ranged bool
+	}{
+		{"no conditions", strong, entity.ObjectRequest{}, nil, false},
+		{"if-match", strong, entity.ObjectRequest{IfMatch: ptr(`"abc"`)}, nil, false},
+		{"if-match list", strong, entity.ObjectRequest{IfMatch: ptr(`"x", "abc"`)}, nil, false},
+		{"if-match star", strong, entity.ObjectRequest{IfMatch: ptr("*")}, nil, false},
+		{"if-match other", strong, entity.ObjectRequest{IfMatch: ptr(`"x"`)}, entity.ErrPreconditionFailed, false},
+		// If-Match uses the strong comparison, weak tags never match.
+		{"if-match weak", weak, entity.ObjectRequest{IfMatch: ptr(weak.ETag())}, entity.ErrPreconditionFailed, false},
+		{"if-none-match", strong, entity.ObjectRequest{IfNoneMatch: ptr(`"abc"`)}, entity.ErrNotModified, false},
+		{"if-none-match weak", strong, entity.ObjectRequest{IfNoneMatch: ptr(`W/"abc"`)}, entity.ErrNotModified, false},
+		{"if-none-match other", strong, entity.ObjectRequest{IfNoneMatch: ptr(`"x"`)}, nil, false},
+		{"if-modified-since same", strong, entity.ObjectRequest{IfModifiedSince: ptr[int64](100)}, entity.ErrNotModified, false},
+		{"if-modified-since older", strong, entity.ObjectRequest{IfModifiedSince: ptr[int64](99)}, nil, false},
+		// If-None-Match takes precedence over If-Modified-Since.
+		{"if-none-match over date", strong, entity.ObjectRequest{IfNoneMatch: ptr(`"x"`), IfModifiedSince: ptr[int64](100)}, nil, false},
+		{"range", strong, entity.ObjectRequest{Range: ptr("bytes=0-1")}, nil, true},
+		{"if-range etag", strong, entity.ObjectRequest{Range: ptr("bytes=0-1"), IfRangeETag: ptr(`"abc"`)}, nil, true},
+		{"if-range other etag", strong, entity.ObjectRequest{Range: ptr("bytes=0-1"), IfRangeETag: ptr(`"x"`)}, nil, false},
+		{"if-range weak etag", weak, entity.ObjectRequest{Range: ptr("bytes=0-1"), IfRangeETag: ptr(weak.ETag())}, nil, false},
+		{"if-range date", strong, entity.ObjectRequest{Range: ptr("bytes=0-1"), IfRangeModified: ptr[int64](100)}, nil, true},
+		{"if-range other date", strong, entity.ObjectRequest{Range: ptr("bytes=0-1"), IfRangeModified: ptr[int64](99)}, nil, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+
+			if err := preconditions(tt.object, &req); !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+			if ranged := req.Range != nil; ranged != tt.ranged {
+				t.Errorf("range kept %t, want %t", ranged, tt.ranged)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// ContentDisplay streams the image to show at full size: the original when
+// accept takes its content type or there is no display rendition, the
+// display rendition otherwise.
+func (p *Photo) ContentDisplay(ctx context.Context, req entity.ObjectRequest, accept func(contentType string) bool) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, displayRendition(accept))
+}
+
+func (p *Photo) ContentDisplayStat(ctx context.Context, req entity.ObjectRequest, accept func(contentType string) bool) (*entity.ObjectReader, error) {
+	return p.stat(ctx, req, displayRendition(accept))
+}
+
+func displayRendition(accept func(contentType string) bool) rendition {
+	return func(c entity.Content) (entity.Object, string) {
+		if c.Display.ID == "" || accept(c.Original.ContentType) {
+			return originalRendition(c)
+		}
+
+		return c.Display, path.Join(ThumbnailsPath, c.Display.ID)
+	}
+}
+
+func (p *Photo) displayTask() task {
+	return task{
+		kind:  "display",
+		event: entity.EventDisplay,
+		applies: func(original entity.Object) bool {
+			return strings.HasPrefix(original.ContentType, "image/") && !entity.IsWebImage(original.ContentType)
+		},
+		done: func(c entity.Content) bool {
+			return c.Display.ID != ""
+		},
+		create: p.display,
+	}
+}
+
+// display creates the display rendition of an image browsers cannot render,
+// see generate.
+func (p *Photo) display(ctx context.Context, file string, original entity.Object, _ string) (store, error) {
+	d := generate(ctx, "display", file, original, p.thumbnail.Display)
+	if d == nil {
+		return nil, nil
+	}
+
+	return func(ctx context.Context) (func(c *entity.Content), error) {
+		display, err := p.uploadFile(ctx, *d, ThumbnailsPath, path.Join(original.ID, "display"+path.Ext(d.Path)), original.LastModified)
+		if err != nil {
+			return nil, fmt.Errorf("upload display: %w", err)
+		}
+
+		return func(c *entity.Content) { c.Display = display }, nil
+	}, nil
+}
@@ -0,0 +1,151 @@
+package photo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const (
+	eventsHistory = 1024
+	eventsBuffer  = 64
+)
+
+type Subscription struct {
+	// Replay holds events missed since the requested event ID.
+	Replay []entity.Event
+	// Events is closed when the subscriber falls too far behind.
+	Events <-chan entity.Event
+
+	bus *bus
+	sub *subscriber
+}
+
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s.sub)
+}
+
+type subscriber struct {
+	viewer entity.Viewer
+	ch     chan entity.Event
+}
+
+// match reports whether the subscriber receives the event. Without an
+// authorization proxy every event is delivered, anonymous subscribers behind
+// one receive none.
+func (s *subscriber) match(e entity.Event) bool {
+	if s.viewer.Anonymous {
+		return false
+	}
+
+	return s.viewer.User == "" || e.User == "" || s.viewer.User == e.User
+}
+
+// visible returns the event as the subscriber may see it.
+func (s *subscriber) visible(e entity.Event) entity.Event {
+	if e.Content != nil {
+		content := e.Content.VisibleTo(s.viewer)
+		e.Content = &content
+	}
+
+	return e
+}
+
+type bus struct {
+	mu      sync.Mutex
+	id      int64
+	history []entity.Event
+	subs    map[*subscriber]struct{}
+}
+
+func newBus() *bus {
+	return &bus{
+		// Event IDs survive restarts well enough for Last-Event-ID resumption.
+		id:   time.Now().UnixMicro(),
+		subs: make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *bus) publish(e entity.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.id++
+	e.ID = b.id
+	e.Time = time.Now().Unix()
+
+	b.history = append(b.history, e)
+	if len(b.history) > eventsHistory {
+		b.history = b.history[len(b.history)-eventsHistory:]
+	}
+
+	for s := range b.subs {
+		if !s.match(e) {
+			continue
+		}
+
+		select {
+		case s.ch <- s.visible(e):
+		default:
+			// Slow consumer: drop it, the client resumes from its last event ID.
+			delete(b.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+func (b *bus) subscribe(viewer entity.Viewer, lastID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := &subscriber{
+		viewer: viewer,
+		ch:     make(chan entity.Event, eventsBuffer),
+	}
+	b.subs[s] = struct{}{}
+
+	var replay []entity.Event
+	if lastID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastID && s.match(e) {
+				replay = append(replay, s.visible(e))
+			}
+		}
+	}
+
+	return &Subscription{
+		Replay: replay,
+		Events: s.ch,
+		bus:    b,
+		sub:    s,
+	}
+}
+
+func (b *bus) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscribe streams catalog events visible to the user from the context,
+// replaying buffered events newer than lastID.
+func (p *Photo) Subscribe(ctx context.Context, lastID int64) *Subscription {
+	return p.events.subscribe(entity.ViewerFromContext(ctx), lastID)
+}
+
+// publish sends an event on behalf of the owner of the content, whoever
+// caused it: the uploader of the other half of a pair or a background task.
+func (p *Photo) publish(t entity.EventType, id, owner string, content *entity.Content) {
+	p.events.publish(entity.Event{
+		Type:      t,
+		ContentID: id,
+		User:      owner,
+		Content:   content,
+	})
+}
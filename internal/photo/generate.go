@@ -0,0 +1,63 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+// generate runs a best effort generator on the staged original, a content is
+// backed up without the renditions that cannot be created. Failures are
+// logged and nil is returned, unsupported media and missing parts are
+// expected and not logged.
+func generate[T any](ctx context.Context, kind, file string, original entity.Object, create func(context.Context, repository.Object) (*T, error)) *T {
+	v, err := create(ctx, repository.Object{
+		Path:        file,
+		ContentType: original.ContentType,
+	})
+	if err != nil {
+		if !errors.Is(err, entity.ErrUnsupportedMedia) && !errors.Is(err, entity.ErrNotFound) {
+			fmt.Printf("Create %s `%s`: %s\n", kind, original.ID, err)
+		}
+		return nil
+	}
+
+	return v
+}
+
+// generateObject stores the file of a best effort generator as name under the
+// prefix of the content in dir, only storage errors fail.
+func (p *Photo) generateObject(ctx context.Context, kind, file string, original entity.Object, dir, name string, create func(context.Context, repository.Object) (*repository.Object, error)) (entity.Object, error) {
+	o := generate(ctx, kind, file, original, create)
+	if o == nil {
+		return entity.Object{}, nil
+	}
+
+	object, err := p.uploadFile(ctx, *o, dir, path.Join(original.ID, name+path.Ext(o.Path)), original.LastModified)
+	if err != nil {
+		return entity.Object{}, fmt.Errorf("upload %s: %w", kind, err)
+	}
+
+	return object, nil
+}
+
+// renditionsDelete removes the display, sprites and stream of a content by
+// prefix, so objects the catalog lost track of, such as sheets beyond a
+// shorter re-upload or a stream that failed halfway, go as well.
+func (p *Photo) renditionsDelete(ctx context.Context, id string) error {
+	for _, prefix := range []string{
+		path.Join(ThumbnailsPath, id, "display"),
+		path.Join(ThumbnailsPath, id, "sprite"),
+		path.Join(HLSPath, id) + "/",
+	} {
+		if err := p.storage.DeletePrefix(ctx, prefix); err != nil {
+			return fmt.Errorf("delete `%s`: %w", prefix, err)
+		}
+	}
+
+	return nil
+}
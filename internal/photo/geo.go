@@ -0,0 +1,99 @@
+package photo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// geoCellsPerTile splits a 256px map tile into cells of 64px.
+const geoCellsPerTile = 4
+
+type geoCell struct {
+	x, y int
+}
+
+// Geo returns contents with a location inside the bounds, grouped into grid
+// cells sized for the requested zoom level.
+func (p *Photo) Geo(ctx context.Context, req entity.GeoRequest) ([]entity.GeoCluster, error) {
+	if req.Zoom != nil && (*req.Zoom < 0 || *req.Zoom > entity.MaxZoom) {
+		return nil, fmt.Errorf("zoom %d out of 0-%d: %w", *req.Zoom, entity.MaxZoom, entity.ErrInvalidInput)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var (
+		viewer   = entity.ViewerFromContext(ctx)
+		clusters []entity.GeoCluster
+		cells    = make(map[geoCell]int)
+		size     float64
+	)
+	if req.Zoom != nil {
+		size = 360 / (math.Exp2(float64(*req.Zoom)) * geoCellsPerTile)
+	}
+
+	for _, c := range p.contents {
+		if req.ExcludeHidden && c.Hidden {
+			continue
+		}
+		if c.Metadata == nil || c.Metadata.Location == nil {
+			continue
+		}
+		if c.LocationPrivate && (req.Shared || !viewer.Owns(c)) {
+			continue
+		}
+
+		l := *c.Metadata.Location
+		if !req.Bounds.Contains(l) {
+			continue
+		}
+
+		if req.Zoom == nil {
+			clusters = append(clusters, newCluster(c))
+			continue
+		}
+
+		cell := geoCell{
+			x: int(math.Floor(l.Longitude / size)),
+			y: int(math.Floor(l.Latitude / size)),
+		}
+		idx, ok := cells[cell]
+		if !ok {
+			cells[cell] = len(clusters)
+			clusters = append(clusters, newCluster(c))
+			continue
+		}
+
+		clusters[idx].Add(l)
+	}
+
+	for i := range clusters {
+		if clusters[i].Count > 1 {
+			clusters[i].Content = nil
+		}
+	}
+	slices.SortFunc(clusters, func(a, b entity.GeoCluster) int { return cmp.Compare(b.Count, a.Count) })
+
+	return clusters, nil
+}
+
+func newCluster(c entity.Content) entity.GeoCluster {
+	l := *c.Metadata.Location
+
+	return entity.GeoCluster{
+		Location: l,
+		Bounds: entity.Bounds{
+			MinLatitude:  l.Latitude,
+			MinLongitude: l.Longitude,
+			MaxLatitude:  l.Latitude,
+			MaxLongitude: l.Longitude,
+		},
+		Count:   1,
+		Content: &c,
+	}
+}
@@ -0,0 +1,86 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+func located(id, owner string, latitude, longitude float64) entity.Content {
+	return entity.Content{
+		Original: entity.Object{ID: id},
+		Owner:    owner,
+		Metadata: &entity.Metadata{
+			Location: &entity.Location{Latitude: latitude, Longitude: longitude},
+		},
+	}
+}
+
+func TestGeo(t *testing.T) {
+	var (
+		porto = located("porto.jpg", "a", 41.15, -8.61)
+		tokyo = located("tokyo.jpg", "b", 35.68, 139.69)
+	)
+	porto.LocationPrivate = true
+	tokyo.Hidden = true
+
+	p := &Photo{contents: []entity.Content{
+		located("lisbon-1.jpg", "a", 38.710, -9.140),
+		located("lisbon-2.jpg", "a", 38.712, -9.138),
+		porto,
+		tokyo,
+		{Original: entity.Object{ID: "unlocated.jpg"}, Owner: "a"},
+	}}
+
+	var (
+		world  = entity.Bounds{MinLatitude: -90, MinLongitude: -180, MaxLatitude: 90, MaxLongitude: 180}
+		europe = entity.Bounds{MinLatitude: 35, MinLongitude: -10, MaxLatitude: 45, MaxLongitude: 0}
+		// Crosses the antimeridian, west of east.
+		pacific = entity.Bounds{MinLatitude: -90, MinLongitude: 100, MaxLatitude: 90, MaxLongitude: -100}
+	)
+
+	for _, tt := range []struct {
+		name   string
+		user   string
+		req    entity.GeoRequest
+		counts []int
+		err    error
+	}{
+		{"points", "a", entity.GeoRequest{Bounds: world}, []int{1, 1, 1, 1}, nil},
+		{"zoom 0", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(0)}, []int{3, 1}, nil},
+		{"zoom 10", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(10)}, []int{2, 1, 1}, nil},
+		{"zoom max", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(entity.MaxZoom)}, []int{1, 1, 1, 1}, nil},
+		{"private to others", "b", entity.GeoRequest{Bounds: world, Zoom: ptr(0)}, []int{2, 1}, nil},
+		{"shared", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(0), Shared: true}, []int{2, 1}, nil},
+		{"exclude hidden", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(0), ExcludeHidden: true}, []int{3}, nil},
+		{"bounds", "a", entity.GeoRequest{Bounds: europe, Zoom: ptr(0)}, []int{3}, nil},
+		{"antimeridian", "a", entity.GeoRequest{Bounds: pacific}, []int{1}, nil},
+		{"zoom negative", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(-1)}, nil, entity.ErrInvalidInput},
+		{"zoom too deep", "a", entity.GeoRequest{Bounds: world, Zoom: ptr(entity.MaxZoom + 1)}, nil, entity.ErrInvalidInput},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters, err := p.Geo(entity.WithUser(context.Background(), tt.user), tt.req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+
+			var counts []int
+			for _, c := range clusters {
+				counts = append(counts, c.Count)
+
+				if (c.Content != nil) != (c.Count == 1) {
+					t.Errorf("cluster of %d with content %t", c.Count, c.Content != nil)
+				}
+				if !c.Bounds.Contains(c.Location) {
+					t.Errorf("centroid %v outside %v", c.Location, c.Bounds)
+				}
+			}
+			if !slices.Equal(counts, tt.counts) {
+				t.Errorf("counts %v, want %v", counts, tt.counts)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package photo
+
+import "sync"
+
+// locks serializes the work on one content ID across storage and catalog
+// updates, entries are dropped once no one holds or waits for them.
+type locks struct {
+	mu sync.Mutex
+	m  map[string]*idLock
+}
+
+type idLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newLocks() *locks {
+	return &locks{
+		m: make(map[string]*idLock),
+	}
+}
+
+func (l *locks) lock(id string) func() {
+	l.mu.Lock()
+	e, ok := l.m[id]
+	if !ok {
+		e = &idLock{}
+		l.m[id] = e
+	}
+	e.refs++
+	l.mu.Unlock()
+
+	e.mu.Lock()
+
+	return func() {
+		e.mu.Unlock()
+
+		l.mu.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(l.m, id)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,81 @@
+package photo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// screenshotRatio is the aspect ratio from which a camera-less image is taken
+// for a phone screenshot, phones are 19.5:9 and taller.
+const screenshotRatio = 1.9
+
+// screenSizes are common display resolutions, long side first.
+var screenSizes = [][2]int{
+	{1280, 720}, {1280, 800}, {1366, 768}, {1440, 900}, {1536, 864}, {1600, 900},
+	{1680, 1050}, {1920, 1080}, {1920, 1200}, {2048, 1536}, {2224, 1668}, {2360, 1640},
+	{2388, 1668}, {2560, 1440}, {2560, 1600}, {2732, 2048}, {2880, 1800}, {3024, 1964},
+	{3456, 2234}, {3840, 2160}, {5120, 2880},
+}
+
+// Memories returns contents captured on the same calendar day in previous
+// years, grouped by year with the most recent year first.
+func (p *Photo) Memories(ctx context.Context, req entity.MemoriesRequest) ([]entity.Memory, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var years = make(map[int][]entity.Content)
+	for _, c := range p.contents {
+		if req.ExcludeHidden && c.Hidden {
+			continue
+		}
+		if req.ExcludeScreenshots && isScreenshot(c) {
+			continue
+		}
+
+		t := time.Unix(c.CapturedAt(), 0).In(req.Date.Location())
+		if t.Year() >= req.Date.Year() || t.Month() != req.Date.Month() || t.Day() != req.Date.Day() {
+			continue
+		}
+
+		years[t.Year()] = append(years[t.Year()], c)
+	}
+
+	var memories = make([]entity.Memory, 0, len(years))
+	for year, contents := range years {
+		slices.SortStableFunc(contents, func(a, b entity.Content) int { return cmp.Compare(a.CapturedAt(), b.CapturedAt()) })
+
+		memories = append(memories, entity.Memory{
+			Year:     year,
+			YearsAgo: req.Date.Year() - year,
+			Contents: visibleContents(contents, entity.ViewerFromContext(ctx)),
+		})
+	}
+	slices.SortFunc(memories, func(a, b entity.Memory) int { return cmp.Compare(b.Year, a.Year) })
+
+	return memories, nil
+}
+
+// isScreenshot guesses screenshots from images without a camera whose
+// dimensions look like a screen.
+func isScreenshot(c entity.Content) bool {
+	if !strings.HasPrefix(c.Original.ContentType, "image/") || c.Metadata == nil {
+		return false
+	}
+
+	m := c.Metadata
+	if m.Make != "" || m.Model != "" || m.Width == 0 || m.Height == 0 {
+		return false
+	}
+
+	long, short := max(m.Width, m.Height), min(m.Width, m.Height)
+	if float64(long)/float64(short) >= screenshotRatio {
+		return true
+	}
+
+	return slices.Contains(screenSizes, [2]int{long, short})
+}
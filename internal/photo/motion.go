@@ -0,0 +1,98 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const MotionsPath = "motions"
+
+// ContentMotion streams the video part of a live or motion photo, see
+// ContentOriginal for preconditions.
+func (p *Photo) ContentMotion(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, motionRendition)
+}
+
+func (p *Photo) ContentMotionStat(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.stat(ctx, req, motionRendition)
+}
+
+// motionRendition resolves to the original of the paired video for live
+// photos and to the extracted video for motion photos.
+func motionRendition(c entity.Content) (entity.Object, string) {
+	if c.Pair != "" {
+		return c.Motion, path.Join(OriginalsPath, c.Motion.ID)
+	}
+
+	return c.Motion, path.Join(MotionsPath, c.Motion.ID)
+}
+
+// pair links the content at idx with the other half of its live photo and
+// returns the index of that half, -1 when there is none. A video extracted
+// from the still is replaced by the paired one and deleted.
+func (p *Photo) pair(ctx context.Context, idx int) int {
+	c := p.contents[idx]
+	if c.Metadata == nil || c.Metadata.ContentIdentifier == "" {
+		return -1
+	}
+
+	still := strings.HasPrefix(c.Original.ContentType, "image/")
+	if !still && !strings.HasPrefix(c.Original.ContentType, "video/") {
+		return -1
+	}
+
+	other := slices.IndexFunc(p.contents, func(o entity.Content) bool {
+		if o.Original.ID == c.Original.ID || o.Metadata == nil || o.Metadata.ContentIdentifier != c.Metadata.ContentIdentifier {
+			return false
+		}
+		if still {
+			return strings.HasPrefix(o.Original.ContentType, "video/")
+		}
+		return strings.HasPrefix(o.Original.ContentType, "image/")
+	})
+	if other == -1 {
+		return -1
+	}
+
+	s, v := idx, other
+	if !still {
+		s, v = other, idx
+	}
+	if p.contents[s].Pair == "" && p.contents[s].Motion.ID != "" {
+		if err := p.storage.Delete(ctx, path.Join(MotionsPath, p.contents[s].Motion.ID)); err != nil {
+			fmt.Printf("Delete extracted motion `%s`: %s\n", p.contents[s].Motion.ID, err)
+		}
+	}
+	p.contents[s].Pair = p.contents[v].Original.ID
+	p.contents[s].Motion = p.contents[v].Original
+	p.contents[v].Pair = p.contents[s].Original.ID
+	p.contents[other].Seq = p.nextSeq()
+
+	return other
+}
+
+// unpair unlinks the other half of a live photo and returns its index, -1
+// when the content is not paired.
+func (p *Photo) unpair(c entity.Content) int {
+	if c.Pair == "" {
+		return -1
+	}
+
+	other := p.contentIndex(c.Pair)
+	if other == -1 || p.contents[other].Pair != c.Original.ID {
+		return -1
+	}
+
+	p.contents[other].Pair = ""
+	if p.contents[other].Motion.ID == c.Original.ID {
+		p.contents[other].Motion = entity.Object{}
+	}
+	p.contents[other].Seq = p.nextSeq()
+
+	return other
+}
@@ -3,17 +3,23 @@ package photo
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
+	"runtime"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/tekig/photo-backup-server/internal/entity"
 	"github.com/tekig/photo-backup-server/internal/repository"
+	"github.com/tekig/photo-backup-server/internal/search"
 )
 
 const (
@@ -21,69 +27,179 @@ const (
 	ThumbnailsPath = "thumbnails"
 	TrushPath      = "trush"
 	ContentName    = "content.json"
+	TombstonesName = "tombstones.json"
 )
 
 type Photo struct {
-	storage   repository.Storage
-	thumbnail repository.Thumbnail
-	contents  []entity.Content
+	storage    repository.Storage
+	thumbnail  repository.Thumbnail
+	metadata   repository.Metadata
+	geocoder   repository.Geocoder
+	transcoder repository.Transcoder
+	contents   []entity.Content
+	// positions maps content IDs to their index in contents.
+	positions  map[string]int
+	tombstones []entity.Tombstone
+	horizon    horizon
+	albums     []entity.Album
+	seq        int64
+	events     *bus
+	queue      *queue
+	tasks      []task
+	index      *search.Index
+	// locks hold a content ID from staging to the catalog commit, processing
+	// bounds the uploads running the thumbnail and metadata tools at once.
+	locks      *locks
+	processing chan struct{}
 
 	mu sync.RWMutex
 }
 
-func New(storage repository.Storage, thumbnail repository.Thumbnail) (*Photo, error) {
-	r, err := storage.Download(context.TODO(), repository.ObjectRequest{
-		Path: ContentName,
-	})
-	if err != nil && !errors.Is(err, entity.ErrNotFound) {
-		return nil, fmt.Errorf("download contents: %w", err)
-	}
-	if r != nil {
-		defer r.Content.Close()
-	}
-
+func New(storage repository.Storage, thumbnail repository.Thumbnail, metadata repository.Metadata, geocoder repository.Geocoder, transcoder repository.Transcoder) (*Photo, error) {
 	var contents = make([]entity.Content, 0)
-	if r != nil {
-		if err := json.NewDecoder(r.Content).Decode(&contents); err != nil {
-			return nil, fmt.Errorf("decode contents: %w", err)
+	if err := download(context.TODO(), storage, ContentName, &contents); err != nil {
+		if !errors.Is(err, entity.ErrNotFound) {
+			return nil, fmt.Errorf("download contents: %w", err)
 		}
-	} else {
 		fmt.Println("Use empty content: contents not found")
 	}
 
-	return &Photo{
-		storage:   storage,
-		thumbnail: thumbnail,
-		contents:  contents,
-	}, nil
+	var tombstones = make([]entity.Tombstone, 0)
+	if err := download(context.TODO(), storage, TombstonesName, &tombstones); err != nil && !errors.Is(err, entity.ErrNotFound) {
+		return nil, fmt.Errorf("download tombstones: %w", err)
+	}
+
+	var h horizon
+	if err := download(context.TODO(), storage, HorizonName, &h); err != nil && !errors.Is(err, entity.ErrNotFound) {
+		return nil, fmt.Errorf("download horizon: %w", err)
+	}
+
+	var albums = make([]entity.Album, 0)
+	if err := download(context.TODO(), storage, AlbumsName, &albums); err != nil && !errors.Is(err, entity.ErrNotFound) {
+		return nil, fmt.Errorf("download albums: %w", err)
+	}
+
+	p := &Photo{
+		storage:    storage,
+		thumbnail:  thumbnail,
+		metadata:   metadata,
+		geocoder:   geocoder,
+		transcoder: transcoder,
+		contents:   contents,
+		tombstones: tombstones,
+		horizon:    h,
+		albums:     albums,
+		events:     newBus(),
+		queue:      newQueue(),
+		index:      search.New(),
+		locks:      newLocks(),
+		processing: make(chan struct{}, runtime.NumCPU()),
+	}
+	p.contentsReindex()
+	p.restoreSeq()
+	if err := p.tombstonesCompact(context.TODO()); err != nil {
+		return nil, fmt.Errorf("tombstones compact: %w", err)
+	}
+	p.tasks = []task{p.displayTask(), p.spritesTask(), p.streamTask()}
+
+	for _, c := range p.contents {
+		p.index.Put(searchDocument(c))
+	}
+
+	go p.backfillPlaces(context.TODO())
+	go p.backfillSizes(context.TODO())
+	go p.work(context.TODO())
+	p.backfill()
+
+	return p, nil
 }
 
 func (p *Photo) Contents(ctx context.Context) ([]entity.Content, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	return p.contents, nil
+	return visibleContents(p.contents, entity.ViewerFromContext(ctx)), nil
 }
 
+// ContentOriginal streams the original. When preconditions stop the request
+// the returned reader still carries the object, without content, so that
+// validators can be sent along with the status.
 func (p *Photo) ContentOriginal(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, originalRendition)
+}
+
+// ContentOriginalStat is ContentOriginal without content, for HEAD requests.
+func (p *Photo) ContentOriginalStat(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.stat(ctx, req, originalRendition)
+}
+
+// ContentThumbnail streams the thumbnail, see ContentOriginal for preconditions.
+func (p *Photo) ContentThumbnail(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, thumbnailRendition)
+}
+
+func (p *Photo) ContentThumbnailStat(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.stat(ctx, req, thumbnailRendition)
+}
+
+// ContentPoster streams a still image: the poster frame of videos and the
+// thumbnail of images.
+func (p *Photo) ContentPoster(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, posterRendition)
+}
+
+func (p *Photo) ContentPosterStat(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.stat(ctx, req, posterRendition)
+}
+
+// rendition picks one of the stored objects of a content and its storage path.
+type rendition func(c entity.Content) (entity.Object, string)
+
+func originalRendition(c entity.Content) (entity.Object, string) {
+	return c.Original, path.Join(OriginalsPath, c.Original.ID)
+}
+
+func thumbnailRendition(c entity.Content) (entity.Object, string) {
+	return c.Thumbnail, path.Join(ThumbnailsPath, c.Thumbnail.ID)
+}
+
+func posterRendition(c entity.Content) (entity.Object, string) {
+	if c.Poster.ID == "" && strings.HasPrefix(c.Thumbnail.ContentType, "image/") {
+		return thumbnailRendition(c)
+	}
+
+	return c.Poster, path.Join(ThumbnailsPath, c.Poster.ID)
+}
+
+func (p *Photo) lookup(id string, r rendition) (entity.Object, string, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	idx := slices.IndexFunc(p.contents, func(c entity.Content) bool { return c.Original.ID == req.ID })
+	idx := p.contentIndex(id)
 	if idx == -1 {
-		return nil, fmt.Errorf("search content: %w", entity.ErrNotFound)
+		return entity.Object{}, "", fmt.Errorf("search content: %w", entity.ErrNotFound)
 	}
 
-	content := p.contents[idx]
+	object, objectPath := r(p.contents[idx])
+	if object.ID == "" {
+		return entity.Object{}, "", fmt.Errorf("search rendition: %w", entity.ErrNotFound)
+	}
 
-	if req.IfModifiedSince != nil {
-		if content.Original.LastModified == *req.IfModifiedSince {
-			return nil, entity.ErrNotModified
-		}
+	return object, objectPath, nil
+}
+
+func (p *Photo) read(ctx context.Context, req entity.ObjectRequest, r rendition) (*entity.ObjectReader, error) {
+	object, objectPath, err := p.lookup(req.ID, r)
+	if err != nil {
+		return nil, err
 	}
 
-	object, err := p.storage.Download(ctx, repository.ObjectRequest{
-		Path:  path.Join(OriginalsPath, req.ID),
+	if err := preconditions(object, &req); err != nil {
+		return &entity.ObjectReader{Object: object}, err
+	}
+
+	response, err := p.storage.Download(ctx, repository.ObjectRequest{
+		Path:  objectPath,
 		Range: req.Range,
 	})
 	if err != nil {
@@ -91,46 +207,45 @@ func (p *Photo) ContentOriginal(ctx context.Context, req entity.ObjectRequest) (
 	}
 
 	return &entity.ObjectReader{
-		Object:        content.Original,
-		Content:       object.Content,
-		ContentLength: object.ContentLength,
-		ContentRange:  object.ContentRange,
+		Object:        object,
+		Content:       response.Content,
+		ContentLength: response.ContentLength,
+		ContentRange:  response.ContentRange,
 	}, nil
 }
 
-func (p *Photo) ContentThumbnail(ctx context.Context, id string, ifModifiedSince *int64) (*entity.ObjectReader, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	idx := slices.IndexFunc(p.contents, func(c entity.Content) bool { return c.Original.ID == id })
-	if idx == -1 {
-		return nil, fmt.Errorf("search content: %w", entity.ErrNotFound)
+func (p *Photo) stat(ctx context.Context, req entity.ObjectRequest, r rendition) (*entity.ObjectReader, error) {
+	object, objectPath, err := p.lookup(req.ID, r)
+	if err != nil {
+		return nil, err
 	}
 
-	content := p.contents[idx]
-
-	if ifModifiedSince != nil {
-		if content.Thumbnail.LastModified == *ifModifiedSince {
-			return nil, entity.ErrNotModified
-		}
+	if err := preconditions(object, &req); err != nil {
+		return &entity.ObjectReader{Object: object}, err
 	}
 
-	object, err := p.storage.Download(ctx, repository.ObjectRequest{
-		Path: path.Join(ThumbnailsPath, content.Thumbnail.ID),
-	})
+	info, err := p.storage.Stat(ctx, objectPath)
 	if err != nil {
-		return nil, fmt.Errorf("download: %w", err)
+		return nil, fmt.Errorf("stat: %w", err)
 	}
 
 	return &entity.ObjectReader{
-		Object:  content.Thumbnail,
-		Content: object.Content,
+		Object:        object,
+		ContentLength: &info.ContentLength,
 	}, nil
 }
 
+// ContentUpload stores the original and its thumbnail. Only the catalog update
+// is done under the lock, so uploads of different contents run in parallel.
 func (p *Photo) ContentUpload(ctx context.Context, original entity.ObjectReader) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	if err := entity.ValidateID(original.ID); err != nil {
+		return fmt.Errorf("validate id: %w", err)
+	}
+
+	// Stored objects and the catalog entry of an ID are written by one
+	// upload or delete at a time, so they never disagree.
+	unlock := p.locks.lock(original.ID)
+	defer unlock()
 
 	tmp, err := os.MkdirTemp("", "photo-*")
 	if err != nil {
@@ -138,13 +253,16 @@ func (p *Photo) ContentUpload(ctx context.Context, original entity.ObjectReader)
 	}
 	defer os.RemoveAll(tmp)
 
-	fOrigin, err := os.Create(path.Join(tmp, original.ID))
+	// The staged name is fixed, tools only look at the extension.
+	fOrigin, err := os.Create(path.Join(tmp, "original"+path.Ext(original.ID)))
 	if err != nil {
 		return fmt.Errorf("create original: %w", err)
 	}
 	defer fOrigin.Close()
 
-	if _, err := io.Copy(fOrigin, original.Content); err != nil {
+	hOrigin := sha256.New()
+	size, err := io.Copy(io.MultiWriter(fOrigin, hOrigin), original.Content)
+	if err != nil {
 		return fmt.Errorf("copy original: %w", err)
 	}
 
@@ -152,6 +270,25 @@ func (p *Photo) ContentUpload(ctx context.Context, original entity.ObjectReader)
 		return fmt.Errorf("seek: %w", err)
 	}
 	original.Content = fOrigin
+	original.Hash = hex.EncodeToString(hOrigin.Sum(nil))
+	original.Size = size
+
+	select {
+	case p.processing <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("wait processing: %w", ctx.Err())
+	}
+	defer func() { <-p.processing }()
+
+	// Metadata is best effort, a file without it is still backed up.
+	metadata, err := p.metadata.Extract(ctx, repository.Object{
+		Path:        fOrigin.Name(),
+		ContentType: original.ContentType,
+	})
+	if err != nil {
+		fmt.Printf("Extract metadata `%s`: %s\n", original.ID, err)
+	}
+	place := p.place(ctx, original.ID, metadata)
 
 	th, err := p.thumbnail.Create(ctx, repository.Object{
 		Path:        fOrigin.Name(),
@@ -166,11 +303,18 @@ func (p *Photo) ContentUpload(ctx context.Context, original entity.ObjectReader)
 	}
 	defer fThumbnail.Close()
 
+	thumbnailHash, thumbnailSize, err := hashFile(fThumbnail)
+	if err != nil {
+		return fmt.Errorf("hash thumbnail: %w", err)
+	}
+
 	thumbnail := entity.ObjectReader{
 		Object: entity.Object{
-			ID:           path.Base(th.Path),
+			ID:           original.ID + path.Ext(th.Path),
 			ContentType:  th.ContentType,
 			LastModified: original.LastModified,
+			Hash:         thumbnailHash,
+			Size:         thumbnailSize,
 		},
 		Content: fThumbnail,
 	}
@@ -191,30 +335,89 @@ func (p *Photo) ContentUpload(ctx context.Context, original entity.ObjectReader)
 		return fmt.Errorf("upload thumbnail: %w", err)
 	}
 
+	poster, err := p.generateObject(ctx, "poster", fOrigin.Name(), original.Object, ThumbnailsPath, "poster", p.thumbnail.Poster)
+	if err != nil {
+		return fmt.Errorf("poster: %w", err)
+	}
+
+	motion, err := p.generateObject(ctx, "motion", fOrigin.Name(), original.Object, MotionsPath, "motion", p.metadata.Motion)
+	if err != nil {
+		return fmt.Errorf("motion: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	content := entity.Content{
+		Seq:       p.nextSeq(),
 		Original:  original.Object,
 		Thumbnail: thumbnail.Object,
+		Poster:    poster,
+		Metadata:  metadata,
+		Place:     place,
+		Motion:    motion,
+		Owner:     entity.UserFromContext(ctx),
 	}
 
-	idx := slices.IndexFunc(p.contents, func(c entity.Content) bool { return c.Original.ID == content.Original.ID })
+	// related are other contents whose pair or stack changed with this one.
+	var related []int
+	idx := p.contentIndex(content.Original.ID)
 	if idx != -1 {
+		if err := p.renditionsDelete(ctx, content.Original.ID); err != nil {
+			return fmt.Errorf("renditions delete: %w", err)
+		}
+
+		content.UserMetadata = p.contents[idx].UserMetadata
+		content.Owner = p.contents[idx].Owner
+		related = append(related, p.unpair(p.contents[idx]), p.unstack(p.contents[idx]))
 		p.contents[idx] = content
 	} else {
+		idx = len(p.contents)
 		p.contents = append(p.contents, content)
+		p.positions[content.Original.ID] = idx
 	}
+	related = append(related, p.pair(ctx, idx))
+
+	stacked, err := p.stack(idx)
+	if err != nil {
+		return fmt.Errorf("stack: %w", err)
+	}
+	related = append(related, stacked...)
+	content = p.contents[idx]
 
 	if err := p.contentsUpload(ctx); err != nil {
 		return fmt.Errorf("contents upload: %w", err)
 	}
 
+	p.index.Put(searchDocument(content))
+
+	if slices.ContainsFunc(p.tombstones, func(t entity.Tombstone) bool { return t.ID == content.Original.ID }) {
+		p.tombstones = slices.DeleteFunc(p.tombstones, func(t entity.Tombstone) bool { return t.ID == content.Original.ID })
+
+		if err := p.tombstonesUpload(ctx); err != nil {
+			return fmt.Errorf("tombstones upload: %w", err)
+		}
+	}
+
+	p.publish(entity.EventUpload, content.Original.ID, content.Owner, &content)
+	p.publish(entity.EventThumbnail, content.Original.ID, content.Owner, &content)
+	for _, c := range p.relatedContents(related, idx) {
+		p.publish(entity.EventMetadata, c.Original.ID, c.Owner, &c)
+	}
+
+	p.enqueue(content)
+
 	return nil
 }
 
 func (p *Photo) ContentDelete(ctx context.Context, id string) error {
+	unlock := p.locks.lock(id)
+	defer unlock()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	idx := slices.IndexFunc(p.contents, func(c entity.Content) bool { return c.Original.ID == id })
+	idx := p.contentIndex(id)
 	if idx == -1 {
 		return nil
 	}
@@ -224,27 +427,185 @@ func (p *Photo) ContentDelete(ctx context.Context, id string) error {
 		return fmt.Errorf("thumbnail delete: %w", err)
 	}
 
+	if content.Poster.ID != "" {
+		if err := p.storage.Delete(ctx, path.Join(ThumbnailsPath, content.Poster.ID)); err != nil {
+			return fmt.Errorf("poster delete: %w", err)
+		}
+	}
+
+	if err := p.renditionsDelete(ctx, content.Original.ID); err != nil {
+		return fmt.Errorf("renditions delete: %w", err)
+	}
+
+	// A paired content references the original of the video, anything under
+	// the motions prefix was extracted from this one.
+	if err := p.storage.DeletePrefix(ctx, path.Join(MotionsPath, content.Original.ID)+"/"); err != nil {
+		return fmt.Errorf("motion delete: %w", err)
+	}
+
 	if err := p.storage.Move(ctx, path.Join(OriginalsPath, content.Original.ID), path.Join(TrushPath, content.Original.ID)); err != nil {
 		return fmt.Errorf("original trush: %w", err)
 	}
 
+	related := p.relatedContents([]int{p.unpair(content), p.unstack(content)}, idx)
+
 	p.contents = slices.DeleteFunc(p.contents, func(c entity.Content) bool { return c.Original.ID == id })
+	p.contentsReindex()
+	p.tombstones = append(p.tombstones, entity.Tombstone{
+		ID:      id,
+		Seq:     p.nextSeq(),
+		Deleted: time.Now().Unix(),
+	})
 
 	if err := p.contentsUpload(ctx); err != nil {
 		return fmt.Errorf("contents upload: %w", err)
 	}
 
+	p.index.Delete(id)
+
+	if err := p.tombstonesUpload(ctx); err != nil {
+		return fmt.Errorf("tombstones upload: %w", err)
+	}
+
+	if err := p.tombstonesCompact(ctx); err != nil {
+		return fmt.Errorf("tombstones compact: %w", err)
+	}
+
+	if p.albumsForget(id) {
+		if err := p.albumsUpload(ctx); err != nil {
+			return fmt.Errorf("albums upload: %w", err)
+		}
+	}
+
+	p.publish(entity.EventDelete, id, content.Owner, nil)
+	for _, c := range related {
+		p.publish(entity.EventMetadata, c.Original.ID, c.Owner, &c)
+	}
+
 	return nil
 }
 
+// visibleContents copies the contents as the viewer may see them.
+func visibleContents(contents []entity.Content, v entity.Viewer) []entity.Content {
+	var visible = make([]entity.Content, 0, len(contents))
+	for _, c := range contents {
+		visible = append(visible, c.VisibleTo(v))
+	}
+
+	return visible
+}
+
+// relatedContents copies the contents at the indexes, skipping -1, self and
+// duplicates.
+func (p *Photo) relatedContents(indexes []int, self int) []entity.Content {
+	slices.Sort(indexes)
+
+	var contents []entity.Content
+	for _, i := range slices.Compact(indexes) {
+		if i != -1 && i != self {
+			contents = append(contents, p.contents[i])
+		}
+	}
+
+	return contents
+}
+
+func (p *Photo) contentIndex(id string) int {
+	if idx, ok := p.positions[id]; ok {
+		return idx
+	}
+
+	return -1
+}
+
+// contentsReindex rebuilds positions from contents, after loading or removing
+// contents. Must be called with the write lock held.
+func (p *Photo) contentsReindex() {
+	p.positions = make(map[string]int, len(p.contents))
+	for i, c := range p.contents {
+		p.positions[c.Original.ID] = i
+	}
+}
+
 func (p *Photo) contentsUpload(ctx context.Context) error {
-	data, err := json.Marshal(p.contents)
+	return upload(ctx, p.storage, ContentName, p.contents)
+}
+
+func (p *Photo) tombstonesUpload(ctx context.Context) error {
+	return upload(ctx, p.storage, TombstonesName, p.tombstones)
+}
+
+// uploadFile stores a file created from the original as id under dir.
+func (p *Photo) uploadFile(ctx context.Context, file repository.Object, dir, id string, lastModified int64) (entity.Object, error) {
+	f, err := os.Open(file.Path)
 	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+		return entity.Object{}, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	hash, size, err := hashFile(f)
+	if err != nil {
+		return entity.Object{}, fmt.Errorf("hash: %w", err)
+	}
+
+	object := entity.Object{
+		ID:           id,
+		ContentType:  file.ContentType,
+		LastModified: lastModified,
+		Hash:         hash,
+		Size:         size,
 	}
 
 	if err := p.storage.Upload(ctx, repository.ObjectReader{
-		Path:        ContentName,
+		Path:        path.Join(dir, object.ID),
+		ContentType: object.ContentType,
+		Content:     f,
+	}); err != nil {
+		return entity.Object{}, fmt.Errorf("upload: %w", err)
+	}
+
+	return object, nil
+}
+
+// hashFile returns the hex SHA-256 and the size of f and rewinds it.
+func hashFile(f *os.File) (string, int64, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("copy: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("seek: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+func download(ctx context.Context, storage repository.Storage, name string, v any) error {
+	r, err := storage.Download(ctx, repository.ObjectRequest{
+		Path: name,
+	})
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	defer r.Content.Close()
+
+	if err := json.NewDecoder(r.Content).Decode(v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	return nil
+}
+
+func upload(ctx context.Context, storage repository.Storage, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := storage.Upload(ctx, repository.ObjectReader{
+		Path:        name,
 		ContentType: "applicaltion/json",
 		Content:     bytes.NewReader(data),
 	}); err != nil {
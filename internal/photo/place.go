@@ -0,0 +1,110 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// place reverse geocodes the metadata location. Like metadata it is best
+// effort, the content is stored without a place on failure.
+func (p *Photo) place(ctx context.Context, id string, metadata *entity.Metadata) *entity.Place {
+	if metadata == nil || metadata.Location == nil {
+		return nil
+	}
+
+	place, err := p.geocoder.Reverse(ctx, *metadata.Location)
+	if err != nil {
+		if !errors.Is(err, entity.ErrNotFound) {
+			fmt.Printf("Reverse geocode `%s`: %s\n", id, err)
+		}
+		return nil
+	}
+
+	return place
+}
+
+// placesBatch is how many backfilled places are applied under one lock and
+// catalog upload.
+const placesBatch = 500
+
+// backfilledPlace is a place found for a content with its original hash, a
+// content re-uploaded meanwhile keeps the place of its new upload.
+type backfilledPlace struct {
+	id    string
+	hash  string
+	place *entity.Place
+}
+
+// backfillPlaces geocodes contents uploaded before reverse geocoding or with a
+// location no city was found for. Geocoding runs outside the lock, places are
+// applied in batches and go through the delta feed.
+func (p *Photo) backfillPlaces(ctx context.Context) {
+	p.mu.RLock()
+	var pending []entity.Content
+	for _, c := range p.contents {
+		if c.Place == nil && c.Metadata != nil && c.Metadata.Location != nil {
+			pending = append(pending, c)
+		}
+	}
+	p.mu.RUnlock()
+
+	var (
+		updated int
+		batch   []backfilledPlace
+	)
+	for _, c := range pending {
+		place := p.place(ctx, c.Original.ID, c.Metadata)
+		if place == nil {
+			continue
+		}
+
+		batch = append(batch, backfilledPlace{id: c.Original.ID, hash: c.Original.Hash, place: place})
+		if len(batch) == placesBatch {
+			updated += p.applyPlaces(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+	updated += p.applyPlaces(ctx, batch)
+
+	if updated != 0 {
+		fmt.Printf("Backfill places: %d contents updated\n", updated)
+	}
+}
+
+// applyPlaces stores the places of contents unchanged since they were
+// geocoded and returns how many were updated.
+func (p *Photo) applyPlaces(ctx context.Context, places []backfilledPlace) int {
+	if len(places) == 0 {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var updated int
+	for _, found := range places {
+		idx := p.contentIndex(found.id)
+		if idx == -1 || p.contents[idx].Original.Hash != found.hash || p.contents[idx].Place != nil {
+			continue
+		}
+
+		p.contents[idx].Place = found.place
+		p.contents[idx].Seq = p.nextSeq()
+		p.index.Put(searchDocument(p.contents[idx]))
+		updated++
+	}
+
+	if updated == 0 {
+		return 0
+	}
+
+	if err := p.contentsUpload(ctx); err != nil {
+		fmt.Printf("Backfill places: contents upload: %s\n", err)
+		return 0
+	}
+
+	return updated
+}
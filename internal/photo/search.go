@@ -0,0 +1,59 @@
+package photo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/search"
+)
+
+// Search returns contents matching the query, newest captures first. See
+// search.Index.Search for the query syntax.
+func (p *Photo) Search(ctx context.Context, query string) ([]entity.Content, error) {
+	ids, err := p.index.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var contents = make([]entity.Content, 0, len(ids))
+	for _, id := range ids {
+		if idx := p.contentIndex(id); idx != -1 {
+			contents = append(contents, p.contents[idx])
+		}
+	}
+
+	slices.SortStableFunc(contents, func(a, b entity.Content) int { return cmp.Compare(b.CapturedAt(), a.CapturedAt()) })
+
+	return visibleContents(contents, entity.ViewerFromContext(ctx)), nil
+}
+
+func searchDocument(c entity.Content) search.Document {
+	doc := search.Document{
+		ID: c.Original.ID,
+		Fields: map[string][]string{
+			search.FieldName:    {path.Base(strings.ReplaceAll(c.Original.ID, "\\", "/"))},
+			search.FieldCaption: {c.Caption},
+			search.FieldTag:     c.Tags,
+			search.FieldType:    {c.Original.ContentType},
+		},
+		Time: c.CapturedAt(),
+	}
+
+	if c.Metadata != nil {
+		doc.Fields[search.FieldCamera] = []string{c.Metadata.Make, c.Metadata.Model}
+	}
+	// Private places are left out, they would be found by anyone.
+	if c.Place != nil && !c.LocationPrivate {
+		doc.Fields[search.FieldPlace] = []string{c.Place.City, c.Place.Region, c.Place.Country}
+	}
+
+	return doc
+}
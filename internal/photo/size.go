@@ -0,0 +1,94 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// sizesBatch is how many backfilled sizes are applied under one lock and
+// catalog upload.
+const sizesBatch = 500
+
+// backfilledSize is the stored size of an original with its hash, a content
+// re-uploaded meanwhile keeps the size of its new upload.
+type backfilledSize struct {
+	id   string
+	hash string
+	size int64
+}
+
+// backfillSizes stats the originals of contents uploaded before sizes were
+// recorded, so that they count in the timeline. Like backfillPlaces storage is
+// read outside the lock and sizes are applied in batches.
+func (p *Photo) backfillSizes(ctx context.Context) {
+	p.mu.RLock()
+	var pending []backfilledSize
+	for _, c := range p.contents {
+		if c.Original.Size == 0 {
+			pending = append(pending, backfilledSize{id: c.Original.ID, hash: c.Original.Hash})
+		}
+	}
+	p.mu.RUnlock()
+
+	var (
+		updated int
+		batch   []backfilledSize
+	)
+	for _, s := range pending {
+		info, err := p.storage.Stat(ctx, path.Join(OriginalsPath, s.id))
+		if err != nil {
+			fmt.Printf("Backfill size `%s`: %s\n", s.id, err)
+			continue
+		}
+		if info.ContentLength == 0 {
+			continue
+		}
+
+		s.size = info.ContentLength
+		batch = append(batch, s)
+		if len(batch) == sizesBatch {
+			updated += p.applySizes(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+	updated += p.applySizes(ctx, batch)
+
+	if updated != 0 {
+		fmt.Printf("Backfill sizes: %d contents updated\n", updated)
+	}
+}
+
+// applySizes stores the sizes of contents unchanged since they were read and
+// returns how many were updated.
+func (p *Photo) applySizes(ctx context.Context, sizes []backfilledSize) int {
+	if len(sizes) == 0 {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var updated int
+	for _, found := range sizes {
+		idx := p.contentIndex(found.id)
+		if idx == -1 || p.contents[idx].Original.Hash != found.hash || p.contents[idx].Original.Size != 0 {
+			continue
+		}
+
+		p.contents[idx].Original.Size = found.size
+		p.contents[idx].Seq = p.nextSeq()
+		updated++
+	}
+
+	if updated == 0 {
+		return 0
+	}
+
+	if err := p.contentsUpload(ctx); err != nil {
+		fmt.Printf("Backfill sizes: contents upload: %s\n", err)
+		return 0
+	}
+
+	return updated
+}
@@ -0,0 +1,85 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// ContentSpritesIndex streams the WebVTT index of the scrubbing sprites, see
+// ContentOriginal for preconditions.
+func (p *Photo) ContentSpritesIndex(ctx context.Context, req entity.ObjectRequest) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, spritesIndexRendition)
+}
+
+// ContentSpritesSheet streams sprite sheet n, counting from 1 as in the index.
+func (p *Photo) ContentSpritesSheet(ctx context.Context, req entity.ObjectRequest, n int) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, spritesSheetRendition(n))
+}
+
+func spritesIndexRendition(c entity.Content) (entity.Object, string) {
+	if c.Sprites == nil {
+		return entity.Object{}, ""
+	}
+
+	return c.Sprites.Index, path.Join(ThumbnailsPath, c.Sprites.Index.ID)
+}
+
+func spritesSheetRendition(n int) rendition {
+	return func(c entity.Content) (entity.Object, string) {
+		if c.Sprites == nil || n < 1 || n > len(c.Sprites.Sheets) {
+			return entity.Object{}, ""
+		}
+
+		sheet := c.Sprites.Sheets[n-1]
+
+		return sheet, path.Join(ThumbnailsPath, sheet.ID)
+	}
+}
+
+func (p *Photo) spritesTask() task {
+	return task{
+		kind:  "sprites",
+		event: entity.EventSprites,
+		applies: func(original entity.Object) bool {
+			return strings.HasPrefix(original.ContentType, "video/")
+		},
+		done: func(c entity.Content) bool {
+			return c.Sprites != nil
+		},
+		create: p.sprites,
+	}
+}
+
+// sprites creates the scrubbing sprites of a video, see generate.
+func (p *Photo) sprites(ctx context.Context, file string, original entity.Object, _ string) (store, error) {
+	s := generate(ctx, "sprites", file, original, p.thumbnail.Sprites)
+	if s == nil {
+		return nil, nil
+	}
+
+	return func(ctx context.Context) (func(c *entity.Content), error) {
+		var sprites = &entity.Sprites{
+			Sheets: make([]entity.Object, 0, len(s.Sheets)),
+		}
+		for i, sheet := range s.Sheets {
+			id := path.Join(original.ID, fmt.Sprintf("sprite-%d%s", i+1, path.Ext(sheet.Path)))
+			object, err := p.uploadFile(ctx, sheet, ThumbnailsPath, id, original.LastModified)
+			if err != nil {
+				return nil, fmt.Errorf("upload sheet: %w", err)
+			}
+			sprites.Sheets = append(sprites.Sheets, object)
+		}
+
+		id := path.Join(original.ID, "sprites"+path.Ext(s.Index.Path))
+		var err error
+		if sprites.Index, err = p.uploadFile(ctx, s.Index, ThumbnailsPath, id, original.LastModified); err != nil {
+			return nil, fmt.Errorf("upload index: %w", err)
+		}
+
+		return func(c *entity.Content) { c.Sprites = sprites }, nil
+	}, nil
+}
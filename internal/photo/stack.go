@@ -0,0 +1,101 @@
+package photo
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// stackTolerance in seconds, cameras may write the RAW and the JPEG of one
+// shot with slightly different timestamps.
+const stackTolerance = 2
+
+// stack groups the content at idx with siblings sharing its base name and
+// capture time when one of them is RAW. It returns the indexes of siblings
+// that joined the stack.
+func (p *Photo) stack(idx int) ([]int, error) {
+	c := p.contents[idx]
+	key := stackKey(c.Original.ID)
+
+	var (
+		siblings []int
+		raw      = entity.IsRaw(c.Original.ContentType)
+		id       string
+	)
+	for i, o := range p.contents {
+		if i == idx || stackKey(o.Original.ID) != key {
+			continue
+		}
+
+		if diff := o.CapturedAt() - c.CapturedAt(); diff < -stackTolerance || diff > stackTolerance {
+			continue
+		}
+
+		siblings = append(siblings, i)
+		raw = raw || entity.IsRaw(o.Original.ContentType)
+		if id == "" {
+			id = o.Stack
+		}
+	}
+
+	if len(siblings) == 0 || !raw {
+		return nil, nil
+	}
+
+	if id == "" {
+		var err error
+		if id, err = newID(); err != nil {
+			return nil, fmt.Errorf("new id: %w", err)
+		}
+	}
+
+	p.contents[idx].Stack = id
+
+	var joined []int
+	for _, i := range siblings {
+		if p.contents[i].Stack == id {
+			continue
+		}
+
+		p.contents[i].Stack = id
+		p.contents[i].Seq = p.nextSeq()
+		joined = append(joined, i)
+	}
+
+	return joined, nil
+}
+
+// unstack removes the content from its stack, a stack of one left behind is
+// dissolved. It returns the index of that last member, -1 otherwise.
+func (p *Photo) unstack(c entity.Content) int {
+	if c.Stack == "" {
+		return -1
+	}
+
+	var members []int
+	for i, o := range p.contents {
+		if o.Stack == c.Stack && o.Original.ID != c.Original.ID {
+			members = append(members, i)
+		}
+	}
+
+	if len(members) != 1 {
+		return -1
+	}
+
+	last := members[0]
+	p.contents[last].Stack = ""
+	p.contents[last].Seq = p.nextSeq()
+
+	return last
+}
+
+// stackKey is the ID without extension, IMG_0001.CR3 and IMG_0001.JPG share
+// IMG_0001.
+func stackKey(id string) string {
+	id = strings.ReplaceAll(id, "\\", "/")
+
+	return strings.ToLower(strings.TrimSuffix(id, path.Ext(id)))
+}
@@ -0,0 +1,129 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+const HLSPath = "hls"
+
+var streamTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+}
+
+// ContentStream streams a file of the HLS stream, the master playlist is
+// the Stream object of the content.
+func (p *Photo) ContentStream(ctx context.Context, req entity.ObjectRequest, name string) (*entity.ObjectReader, error) {
+	return p.read(ctx, req, streamRendition(name))
+}
+
+func streamRendition(name string) rendition {
+	return func(c entity.Content) (entity.Object, string) {
+		if c.Stream.ID == "" || name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+			return entity.Object{}, ""
+		}
+
+		objectPath := path.Join(HLSPath, c.Original.ID, name)
+		if name == c.Stream.ID {
+			return c.Stream, objectPath
+		}
+
+		contentType, ok := streamTypes[path.Ext(name)]
+		if !ok {
+			return entity.Object{}, ""
+		}
+
+		return entity.Object{
+			ID:           name,
+			ContentType:  contentType,
+			LastModified: c.Stream.LastModified,
+		}, objectPath
+	}
+}
+
+func (p *Photo) streamTask() task {
+	return task{
+		kind:  "stream",
+		event: entity.EventStream,
+		applies: func(original entity.Object) bool {
+			return strings.HasPrefix(original.ContentType, "video/")
+		},
+		done: func(c entity.Content) bool {
+			return c.Stream.ID != ""
+		},
+		create: p.stream,
+	}
+}
+
+// stream transcodes a video into an HLS stream, the previous stream of the
+// content is replaced as a whole.
+func (p *Photo) stream(ctx context.Context, file string, original entity.Object, dir string) (store, error) {
+	master, err := p.transcoder.HLS(ctx, repository.Object{
+		Path:        file,
+		ContentType: original.ContentType,
+	}, dir)
+	if errors.Is(err, entity.ErrUnsupportedMedia) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+
+	return func(ctx context.Context) (func(c *entity.Content), error) {
+		prefix := path.Join(HLSPath, original.ID)
+		if err := p.storage.DeletePrefix(ctx, prefix+"/"); err != nil {
+			return nil, fmt.Errorf("delete previous stream: %w", err)
+		}
+
+		stream, err := p.uploadDir(ctx, dir, prefix, original.LastModified, path.Base(master.Path))
+		if err != nil {
+			return nil, fmt.Errorf("upload stream: %w", err)
+		}
+
+		return func(c *entity.Content) { c.Stream = stream }, nil
+	}, nil
+}
+
+// uploadDir stores every file of the transcoder output and returns the
+// object of master.
+func (p *Photo) uploadDir(ctx context.Context, src, dst string, lastModified int64, master string) (entity.Object, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return entity.Object{}, fmt.Errorf("read dir: %w", err)
+	}
+
+	var stream entity.Object
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		object, err := p.uploadFile(ctx, repository.Object{
+			Path:        path.Join(src, entry.Name()),
+			ContentType: streamTypes[path.Ext(entry.Name())],
+		}, dst, entry.Name(), lastModified)
+		if err != nil {
+			return entity.Object{}, fmt.Errorf("upload `%s`: %w", entry.Name(), err)
+		}
+
+		if entry.Name() == master {
+			stream = object
+		}
+	}
+
+	if stream.ID == "" {
+		return entity.Object{}, fmt.Errorf("master playlist `%s`: %w", master, entity.ErrNotFound)
+	}
+
+	return stream, nil
+}
@@ -0,0 +1,74 @@
+package photo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// Timeline counts contents per capture date in the given location, newest
+// buckets first.
+func (p *Photo) Timeline(ctx context.Context, granularity entity.Granularity, loc *time.Location) ([]entity.TimelineBucket, error) {
+	bucket, err := bucketFunc(granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var buckets = make(map[string]*entity.TimelineBucket)
+	for _, c := range p.contents {
+		start, key := bucket(time.Unix(c.CapturedAt(), 0).In(loc))
+
+		b, ok := buckets[key]
+		if !ok {
+			b = &entity.TimelineBucket{
+				Key:   key,
+				Start: start.Unix(),
+				Types: make(map[string]int),
+			}
+			buckets[key] = b
+		}
+
+		class, _, _ := strings.Cut(c.Original.ContentType, "/")
+		b.Count++
+		b.Bytes += c.Original.Size
+		b.Types[class]++
+	}
+
+	var timeline = make([]entity.TimelineBucket, 0, len(buckets))
+	for _, b := range buckets {
+		timeline = append(timeline, *b)
+	}
+	slices.SortFunc(timeline, func(a, b entity.TimelineBucket) int { return cmp.Compare(b.Start, a.Start) })
+
+	return timeline, nil
+}
+
+func bucketFunc(granularity entity.Granularity) (func(t time.Time) (time.Time, string), error) {
+	switch granularity {
+	case entity.GranularityYear:
+		return func(t time.Time) (time.Time, string) {
+			start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+			return start, start.Format("2006")
+		}, nil
+	case entity.GranularityMonth, "":
+		return func(t time.Time) (time.Time, string) {
+			start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+			return start, start.Format("2006-01")
+		}, nil
+	case entity.GranularityDay:
+		return func(t time.Time) (time.Time, string) {
+			start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+			return start, start.Format("2006-01-02")
+		}, nil
+	default:
+		return nil, fmt.Errorf("granularity `%s`: %w", granularity, entity.ErrInvalidInput)
+	}
+}
@@ -0,0 +1,81 @@
+package photo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const maxRating = 5
+
+// ContentUpdate applies user metadata. The change is recorded in the delta
+// feed like an upload.
+func (p *Photo) ContentUpdate(ctx context.Context, id string, update entity.UserMetadataUpdate) (*entity.Content, error) {
+	if update.Rating != nil && (*update.Rating < 0 || *update.Rating > maxRating) {
+		return nil, fmt.Errorf("rating %d out of 0-%d: %w", *update.Rating, maxRating, entity.ErrInvalidInput)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.contentIndex(id)
+	if idx == -1 {
+		return nil, fmt.Errorf("search content: %w", entity.ErrNotFound)
+	}
+
+	content := p.contents[idx]
+	viewer := entity.ViewerFromContext(ctx)
+	if update.LocationPrivate != nil && !viewer.Owns(content) {
+		return nil, fmt.Errorf("location privacy of `%s`: %w", id, entity.ErrForbidden)
+	}
+
+	if update.Favorite != nil {
+		content.Favorite = *update.Favorite
+	}
+	if update.Rating != nil {
+		content.Rating = *update.Rating
+	}
+	if update.Caption != nil {
+		content.Caption = strings.TrimSpace(*update.Caption)
+	}
+	if update.Tags != nil {
+		content.Tags = normalizeTags(*update.Tags)
+	}
+	if update.Hidden != nil {
+		content.Hidden = *update.Hidden
+	}
+	if update.LocationPrivate != nil {
+		content.LocationPrivate = *update.LocationPrivate
+	}
+	content.Seq = p.nextSeq()
+
+	p.contents[idx] = content
+
+	if err := p.contentsUpload(ctx); err != nil {
+		return nil, fmt.Errorf("contents upload: %w", err)
+	}
+
+	p.index.Put(searchDocument(content))
+	p.publish(entity.EventMetadata, id, content.Owner, &content)
+
+	content = content.VisibleTo(viewer)
+
+	return &content, nil
+}
+
+// normalizeTags trims tags and drops empty and case-insensitive duplicates.
+func normalizeTags(tags []string) []string {
+	var normalized = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.ContainsFunc(normalized, func(t string) bool { return strings.EqualFold(t, tag) }) {
+			continue
+		}
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+const (
+	// displaySize is the longest side of display renditions.
+	displaySize = 2048
+	// minDisplayPreviewSize is the shortest side an embedded preview needs to
+	// be used for a display rendition, a 4:3 image displaySize wide.
+	minDisplayPreviewSize = displaySize * 3 / 4
+)
+
+// Display creates a JPEG browsers can render of images they cannot, such as
+// HEIC, TIFF and RAW. Videos and web images are entity.ErrUnsupportedMedia.
+func (c *CMD) Display(ctx context.Context, original repository.Object) (*repository.Object, error) {
+	if !strings.HasPrefix(original.ContentType, "image/") || entity.IsWebImage(original.ContentType) {
+		return nil, fmt.Errorf("content type `%s`: %w", original.ContentType, entity.ErrUnsupportedMedia)
+	}
+
+	dir, name := path.Split(original.Path)
+	display := path.Join(dir, name+".display.jpg")
+
+	source, err := displaySource(ctx, original.Path, original.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("display source: %w", err)
+	}
+
+	args := append(source.args(),
+		"-auto-orient",
+		"-profile", c.iccProfile,
+		"-resize", fmt.Sprintf("%dx%d>", displaySize, displaySize),
+		"-strip",
+		"-quality", "85",
+		display,
+	)
+
+	if err := cmd(ctx, "magick", args...); err != nil {
+		return nil, fmt.Errorf("magick convert: %w", err)
+	}
+
+	return &repository.Object{
+		Path:        display,
+		ContentType: "image/jpeg",
+	}, nil
+}
+
+// displaySource returns the full size preview of RAW files when the camera
+// embedded one, a full size decode otherwise. Other formats are decoded by
+// magick itself.
+func displaySource(ctx context.Context, file, contentType string) (*source, error) {
+	if !entity.IsRaw(contentType) {
+		return &source{file: file, route: sourceDecode}, nil
+	}
+
+	preview, err := embeddedPreview(ctx, file, minDisplayPreviewSize)
+	if err != nil {
+		return nil, fmt.Errorf("embedded preview: %w", err)
+	}
+	if preview != nil {
+		return preview, nil
+	}
+
+	decoded := file + ".ppm"
+	if err := dcraw(ctx, file, decoded); err != nil {
+		return nil, fmt.Errorf("dcraw: %w", err)
+	}
+
+	return &source{file: decoded, route: sourceDcraw}, nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+const (
+	hlsMaster        = "master.m3u8"
+	hlsSegmentLength = 6
+)
+
+// hlsRung is one H.264 variant, size is the shortest side.
+type hlsRung struct {
+	size    int
+	bitrate int
+}
+
+// hlsLadder is ordered from the best variant, rungs above the source size are
+// skipped.
+var hlsLadder = []hlsRung{
+	{size: 1080, bitrate: 5000},
+	{size: 720, bitrate: 2800},
+	{size: 480, bitrate: 1400},
+	{size: 360, bitrate: 800},
+}
+
+// HLS transcodes to an H.264/AAC ladder in MPEG-TS segments. Every file is
+// written flat into dir so that playlists reference each other by name.
+func (c *CMD) HLS(ctx context.Context, original repository.Object, dir string) (*repository.Object, error) {
+	if !strings.HasPrefix(original.ContentType, "video/") {
+		return nil, fmt.Errorf("content type `%s`: %w", original.ContentType, entity.ErrUnsupportedMedia)
+	}
+
+	info, err := probeVideo(ctx, original.Path)
+	if err != nil {
+		return nil, fmt.Errorf("probe video: %w", err)
+	}
+
+	var rungs []hlsRung
+	for _, rung := range hlsLadder {
+		if rung.size <= min(info.Width, info.Height) {
+			rungs = append(rungs, rung)
+		}
+	}
+	if len(rungs) == 0 {
+		rungs = hlsLadder[len(hlsLadder)-1:]
+	}
+
+	// One decode, rotated and tone mapped once, split into every rung.
+	var (
+		graph   = fmt.Sprintf("[0:v]%s,split=%d", info.filter(), len(rungs))
+		scales  []string
+		streams []string
+		args    = []string{"-noautorotate", "-i", original.Path}
+	)
+	for i := range rungs {
+		graph += fmt.Sprintf("[s%d]", i)
+	}
+	for i, rung := range rungs {
+		scales = append(scales, fmt.Sprintf(
+			"[s%d]scale='if(gt(iw,ih),-2,%d)':'if(gt(iw,ih),%d,-2)'[v%d]",
+			i, rung.size, rung.size, i,
+		))
+
+		args = append(args,
+			"-map", fmt.Sprintf("[v%d]", i),
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", rung.bitrate),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", rung.bitrate*107/100),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", rung.bitrate*3/2),
+		)
+
+		stream := fmt.Sprintf("v:%d", i)
+		if info.Audio {
+			args = append(args, "-map", "0:a:0")
+			stream += fmt.Sprintf(",a:%d", i)
+		}
+		streams = append(streams, stream)
+	}
+
+	args = append(args,
+		"-filter_complex", strings.Join(append([]string{graph}, scales...), ";"),
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-profile:v", "high",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentLength),
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-ac", "2",
+		"-map_metadata", "-1",
+		"-f", "hls",
+		"-hls_time", fmt.Sprint(hlsSegmentLength),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_type", "mpegts",
+		"-hls_segment_filename", path.Join(dir, "stream_%v_%05d.ts"),
+		"-master_pl_name", hlsMaster,
+		"-var_stream_map", strings.Join(streams, " "),
+		path.Join(dir, "stream_%v.m3u8"),
+	)
+
+	if err := cmd(ctx, "ffmpeg", args...); err != nil {
+		return nil, fmt.Errorf("ffmpeg hls: %w", err)
+	}
+
+	return &repository.Object{
+		Path:        path.Join(dir, hlsMaster),
+		ContentType: "application/vnd.apple.mpegurl",
+	}, nil
+}
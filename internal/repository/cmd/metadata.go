@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+var exifTags = []string{
+	"-DateTimeOriginal",
+	"-OffsetTimeOriginal",
+	"-CreationDate",
+	"-CreateDate",
+	"-Make",
+	"-Model",
+	"-ImageWidth",
+	"-ImageHeight",
+	"-Duration",
+	"-Composite:GPSLatitude",
+	"-Composite:GPSLongitude",
+	"-ContentIdentifier",
+}
+
+var exifDateLayouts = []string{
+	"2006:01:02 15:04:05Z07:00",
+	"2006:01:02 15:04:05.999999999Z07:00",
+	"2006:01:02 15:04:05",
+	"2006:01:02 15:04:05.999999999",
+}
+
+// exifInfo is one object of `exiftool -json -n` output.
+type exifInfo map[string]any
+
+func (c *CMD) Extract(ctx context.Context, original repository.Object) (*entity.Metadata, error) {
+	info, err := exiftool(ctx, original.Path, exifTags...)
+	if err != nil {
+		return nil, fmt.Errorf("exiftool: %w", err)
+	}
+
+	metadata := &entity.Metadata{
+		Make:     info.string("Make"),
+		Model:    info.string("Model"),
+		Width:    int(info.float("ImageWidth")),
+		Height:   int(info.float("ImageHeight")),
+		Duration: info.float("Duration"),
+
+		ContentIdentifier: info.string("ContentIdentifier"),
+	}
+
+	// QuickTime CreateDate is UTC while CreationDate carries the local offset.
+	for _, tag := range []string{"DateTimeOriginal", "CreationDate", "CreateDate"} {
+		if t, ok := info.date(tag, info.string("OffsetTimeOriginal")); ok {
+			metadata.CapturedAt = t.Unix()
+			break
+		}
+	}
+
+	if _, ok := info["GPSLatitude"]; ok {
+		location := entity.Location{
+			Latitude:  info.float("GPSLatitude"),
+			Longitude: info.float("GPSLongitude"),
+		}
+		if location.Latitude != 0 || location.Longitude != 0 {
+			metadata.Location = &location
+		}
+	}
+
+	return metadata, nil
+}
+
+func exiftool(ctx context.Context, file string, args ...string) (exifInfo, error) {
+	args = append([]string{"-json", "-n", "-api", "LargeFileSupport=1"}, args...)
+	args = append(args, file)
+
+	cmd := exec.CommandContext(ctx, "exiftool", args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run stderr=`%s`: %w", stderr.String(), err)
+	}
+
+	var infos []exifInfo
+	if err := json.Unmarshal(stdout.Bytes(), &infos); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if len(infos) == 0 {
+		return exifInfo{}, nil
+	}
+
+	return infos[0], nil
+}
+
+func (i exifInfo) string(tag string) string {
+	switch v := i[tag].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprint(v)
+	}
+
+	return ""
+}
+
+func (i exifInfo) float(tag string) float64 {
+	switch v := i[tag].(type) {
+	case float64:
+		return v
+	case string:
+		var f float64
+		fmt.Sscan(v, &f)
+		return f
+	}
+
+	return 0
+}
+
+// date parses an EXIF date. Dates without an offset use the given one and
+// are taken as UTC when there is none.
+func (i exifInfo) date(tag, offset string) (time.Time, bool) {
+	v := i.string(tag)
+	if v == "" || strings.HasPrefix(v, "0000") {
+		return time.Time{}, false
+	}
+
+	for _, layout := range exifDateLayouts {
+		candidate := v
+		if offset != "" && !strings.Contains(layout, "Z07:00") {
+			candidate, layout = v+offset, layout+"Z07:00"
+		}
+
+		if t, err := time.Parse(layout, candidate); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
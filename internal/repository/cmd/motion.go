@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+// motionTags locate a video appended to a motion photo: Google MicroVideo
+// stores its offset from the end of the file, Motion Photo v1 lists it in the
+// container directory and Samsung keeps it in a trailer.
+var motionTags = []string{
+	"-XMP-GCamera:MicroVideo",
+	"-XMP-GCamera:MicroVideoOffset",
+	"-XMP-GCamera:MotionPhoto",
+	"-XMP-Container:DirectoryItemSemantic",
+	"-XMP-Container:DirectoryItemLength",
+	"-Samsung:EmbeddedVideoFile",
+}
+
+// Motion extracts the video embedded in an Android motion photo.
+func (c *CMD) Motion(ctx context.Context, original repository.Object) (*repository.Object, error) {
+	if !strings.HasPrefix(original.ContentType, "image/") {
+		return nil, fmt.Errorf("content type `%s`: %w", original.ContentType, entity.ErrNotFound)
+	}
+
+	info, err := exiftool(ctx, original.Path, motionTags...)
+	if err != nil {
+		return nil, fmt.Errorf("exiftool: %w", err)
+	}
+
+	dir, name := path.Split(original.Path)
+	motion := &repository.Object{
+		Path:        path.Join(dir, name+".mp4"),
+		ContentType: "video/mp4",
+	}
+
+	if offset := info.motionOffset(); offset > 0 {
+		if err := extractTail(original.Path, motion.Path, offset); err != nil {
+			return nil, fmt.Errorf("extract tail: %w", err)
+		}
+
+		return motion, nil
+	}
+
+	if _, ok := info["EmbeddedVideoFile"]; ok {
+		if err := exiftoolBinary(ctx, original.Path, motion.Path, "-Samsung:EmbeddedVideoFile"); err != nil {
+			return nil, fmt.Errorf("exiftool embedded video: %w", err)
+		}
+
+		return motion, nil
+	}
+
+	return nil, fmt.Errorf("motion video: %w", entity.ErrNotFound)
+}
+
+// motionOffset is the size of the video at the end of the file, 0 if none.
+func (i exifInfo) motionOffset() int64 {
+	if i.float("MicroVideo") == 1 {
+		return int64(i.float("MicroVideoOffset"))
+	}
+
+	if i.float("MotionPhoto") == 1 {
+		semantics, lengths := i.list("DirectoryItemSemantic"), i.list("DirectoryItemLength")
+		for n, semantic := range semantics {
+			if fmt.Sprint(semantic) == "MotionPhoto" && n < len(lengths) {
+				if length, ok := lengths[n].(float64); ok {
+					return int64(length)
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+// list returns a list tag, a single value is a list of one.
+func (i exifInfo) list(tag string) []any {
+	switch v := i[tag].(type) {
+	case []any:
+		return v
+	case nil:
+		return nil
+	default:
+		return []any{v}
+	}
+}
+
+// extractTail copies the last size bytes of src to dst, checking that they
+// start with an MP4 file type box.
+func extractTail(src, dst string, size int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	if size > stat.Size() {
+		return fmt.Errorf("offset %d beyond size %d: %w", size, stat.Size(), entity.ErrNotFound)
+	}
+
+	var box [8]byte
+	if _, err := f.ReadAt(box[:], stat.Size()-size); err != nil {
+		return fmt.Errorf("read box: %w", err)
+	}
+	if string(box[4:]) != "ftyp" {
+		return fmt.Errorf("no ftyp box at offset %d: %w", size, entity.ErrNotFound)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, io.NewSectionReader(f, stat.Size()-size, size)); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	return nil
+}
+
+// exiftoolBinary writes the binary value of a tag to dst.
+func exiftoolBinary(ctx context.Context, file, dst, tag string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer out.Close()
+
+	cmd := exec.CommandContext(ctx, "exiftool", "-b", tag, file)
+
+	var stderr bytes.Buffer
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run stderr=`%s`: %w", stderr.String(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// minPreviewSize is the shortest side an embedded preview needs to be used
+// for a 256px thumbnail.
+const minPreviewSize = 256
+
+// Thumbnail sources counted in thumbnailSources.
+const (
+	sourceEmbedded = "embedded"
+	sourceHEIF     = "heif_thumbnailer"
+	sourceDcraw    = "dcraw"
+	sourceDecode   = "decode"
+	sourceVideo    = "video"
+)
+
+// thumbnailSources counts which path created thumbnails, see /metrics.
+var thumbnailSources = expvar.NewMap("thumbnail_sources")
+
+// previewTags are embedded JPEGs, largest first. The EXIF thumbnail is
+// usually 160px. MPF images are left out: in HDR JPEGs of phones the second
+// one is the greyscale gain map, not a preview.
+var previewTags = []string{
+	"-JpgFromRaw",
+	"-PreviewImage",
+	"-OtherImage",
+	"-ThumbnailImage",
+}
+
+// orientations are magick -orient values of EXIF orientations 1 to 8.
+var orientations = []string{
+	"TopLeft", "TopRight", "BottomRight", "BottomLeft",
+	"LeftTop", "RightTop", "RightBottom", "LeftBottom",
+}
+
+type source struct {
+	file  string
+	route string
+	// orientation overrides the one of the file, previews are stored upright
+	// relative to the sensor and carry no orientation of their own.
+	orientation string
+	// profile is the ICC profile of the original, previews carry none and
+	// would be taken for sRGB instead of being converted.
+	profile string
+}
+
+// args are the magick arguments reading the source upright and in the color
+// space of the original.
+func (s *source) args() []string {
+	var args = []string{s.file}
+	if s.orientation != "" {
+		args = append(args, "-orient", s.orientation)
+	}
+	if s.profile != "" {
+		args = append(args, "-profile", s.profile)
+	}
+
+	return args
+}
+
+// imageSource returns a file magick can decode quickly and the path taken:
+// an embedded preview when it is large enough, the HEIF thumbnail item, a
+// half size RAW decode or the original itself.
+func imageSource(ctx context.Context, file, contentType string) (*source, error) {
+	preview, err := embeddedPreview(ctx, file, minPreviewSize)
+	if err != nil {
+		return nil, fmt.Errorf("embedded preview: %w", err)
+	}
+	if preview != nil {
+		return preview, nil
+	}
+
+	switch {
+	case entity.IsRaw(contentType):
+		// dcraw rotates the decoded image itself and outputs sRGB.
+		decoded := file + ".ppm"
+		if err := dcraw(ctx, file, decoded, "-h"); err != nil {
+			return nil, fmt.Errorf("dcraw: %w", err)
+		}
+
+		return &source{file: decoded, route: sourceDcraw}, nil
+	case contentType == "image/heic" || contentType == "image/heif" || contentType == "image/avif":
+		// heif-thumbnailer decodes the primary image only without a large
+		// enough thumbnail item, the result is already rotated. When it
+		// fails magick decodes the original through libheif, slower.
+		thumbnail := file + ".thumbnail.png"
+		if err := cmd(ctx, "heif-thumbnailer", "-s", fmt.Sprint(minPreviewSize), file, thumbnail); err != nil {
+			fmt.Printf("Heif-thumbnailer `%s`, decoding the original: %s\n", file, err)
+			return &source{file: file, route: sourceDecode}, nil
+		}
+
+		profile, err := iccProfile(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("icc profile: %w", err)
+		}
+
+		return &source{file: thumbnail, route: sourceHEIF, profile: profile}, nil
+	default:
+		return &source{file: file, route: sourceDecode}, nil
+	}
+}
+
+// embeddedPreview extracts the first embedded preview at least minSize on
+// the shortest side, nil when there is none.
+func embeddedPreview(ctx context.Context, file string, minSize int) (*source, error) {
+	info, err := exiftool(ctx, file, append([]string{"-Orientation"}, previewTags...)...)
+	if err != nil {
+		return nil, fmt.Errorf("exiftool: %w", err)
+	}
+
+	preview := &source{
+		file:  file + ".preview.jpg",
+		route: sourceEmbedded,
+	}
+	if o := int(info.float("Orientation")); o >= 1 && o <= len(orientations) {
+		preview.orientation = orientations[o-1]
+	}
+
+	for _, tag := range previewTags {
+		if _, ok := info[tagName(tag)]; !ok {
+			continue
+		}
+
+		if err := exiftoolBinary(ctx, file, preview.file, tag); err != nil {
+			return nil, fmt.Errorf("exiftool %s: %w", tag, err)
+		}
+
+		if large, err := largeEnough(preview.file, minSize); err == nil && large {
+			if preview.profile, err = iccProfile(ctx, file); err != nil {
+				return nil, fmt.Errorf("icc profile: %w", err)
+			}
+
+			return preview, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// iccProfile extracts the ICC profile of file, empty when it has none.
+func iccProfile(ctx context.Context, file string) (string, error) {
+	profile := file + ".icc"
+	if err := exiftoolBinary(ctx, file, profile, "-ICC_Profile"); err != nil {
+		return "", fmt.Errorf("exiftool: %w", err)
+	}
+
+	info, err := os.Stat(profile)
+	if err != nil {
+		return "", fmt.Errorf("stat: %w", err)
+	}
+	if info.Size() == 0 {
+		return "", nil
+	}
+
+	return profile, nil
+}
+
+// tagName is the JSON key of an exiftool tag argument such as -MPF:MPImage2.
+func tagName(tag string) string {
+	tag = strings.TrimPrefix(tag, "-")
+	if _, name, ok := strings.Cut(tag, ":"); ok {
+		return name
+	}
+
+	return tag
+}
+
+func largeEnough(file string, minSize int) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return false, fmt.Errorf("decode config: %w", err)
+	}
+
+	return min(config.Width, config.Height) >= minSize, nil
+}
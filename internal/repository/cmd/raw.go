@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// dcraw decodes with camera white balance, args such as -h for a half size
+// decode are passed through.
+func dcraw(ctx context.Context, file, dst string, args ...string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer out.Close()
+
+	cmd := exec.CommandContext(ctx, "dcraw", append(append([]string{"-c", "-w"}, args...), file)...)
+
+	var stderr bytes.Buffer
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run stderr=`%s`: %w", stderr.String(), err)
+	}
+
+	return nil
+}
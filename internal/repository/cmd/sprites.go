@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+const (
+	spriteWidth   = 160
+	spriteColumns = 10
+	spriteRows    = 10
+)
+
+// Sprites tiles one frame per interval into sheets of 10x10 frames of 160px
+// width and writes the WebVTT index. Videos shorter than two intervals have
+// nothing to scrub.
+func (c *CMD) Sprites(ctx context.Context, original repository.Object) (*repository.Sprites, error) {
+	if !strings.HasPrefix(original.ContentType, "video/") {
+		return nil, fmt.Errorf("content type `%s`: %w", original.ContentType, entity.ErrUnsupportedMedia)
+	}
+
+	info, err := probeVideo(ctx, original.Path)
+	if err != nil {
+		return nil, fmt.Errorf("probe video: %w", err)
+	}
+
+	interval := c.spriteInterval.Seconds()
+	if info.Duration < 2*interval || info.Width == 0 || info.Height == 0 {
+		return nil, fmt.Errorf("duration %.1fs: %w", info.Duration, entity.ErrUnsupportedMedia)
+	}
+
+	width, height := info.Width, info.Height
+	if info.Rotation == 90 || info.Rotation == 270 {
+		width, height = height, width
+	}
+	tileHeight := int(math.Round(float64(spriteWidth)*float64(height)/float64(width)/2)) * 2
+
+	dir, name := path.Split(original.Path)
+	sheets := path.Join(dir, name+".sprite-%d.jpg")
+
+	if err := cmd(
+		ctx, "ffmpeg",
+		"-noautorotate",
+		"-i", original.Path,
+		"-an",
+		"-map_metadata", "-1",
+		"-vf", info.filter(
+			fmt.Sprintf("fps=1/%g", interval),
+			fmt.Sprintf("scale=%d:%d", spriteWidth, tileHeight),
+			fmt.Sprintf("tile=%dx%d", spriteColumns, spriteRows),
+		),
+		"-q:v", "5",
+		sheets,
+	); err != nil {
+		return nil, fmt.Errorf("ffmpeg sprites: %w", err)
+	}
+
+	sprites := &repository.Sprites{
+		Index: repository.Object{
+			Path:        path.Join(dir, name+".sprites.vtt"),
+			ContentType: "text/vtt",
+		},
+	}
+	for n := 1; ; n++ {
+		sheet := fmt.Sprintf(sheets, n)
+		if _, err := os.Stat(sheet); err != nil {
+			break
+		}
+
+		sprites.Sheets = append(sprites.Sheets, repository.Object{
+			Path:        sheet,
+			ContentType: "image/jpeg",
+		})
+	}
+	if len(sprites.Sheets) == 0 {
+		return nil, fmt.Errorf("ffmpeg sprites: no sheet: %w", entity.ErrUnsupportedMedia)
+	}
+
+	frames := int(math.Ceil(info.Duration / interval))
+	frames = min(frames, len(sprites.Sheets)*spriteColumns*spriteRows)
+
+	if err := os.WriteFile(sprites.Index.Path, spritesIndex(frames, interval, info.Duration, tileHeight), 0o644); err != nil {
+		return nil, fmt.Errorf("write index: %w", err)
+	}
+
+	return sprites, nil
+}
+
+// spritesIndex maps every interval to its tile, sheets are referenced as
+// `sprites/n` relative to the index.
+func spritesIndex(frames int, interval, duration float64, tileHeight int) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+
+	perSheet := spriteColumns * spriteRows
+	for i := range frames {
+		start := float64(i) * interval
+		end := min(start+interval, duration)
+		tile := i % perSheet
+
+		fmt.Fprintf(&b, "\n%s --> %s\nsprites/%d#xywh=%d,%d,%d,%d\n",
+			vttTime(start), vttTime(end),
+			i/perSheet+1,
+			tile%spriteColumns*spriteWidth, tile/spriteColumns*tileHeight,
+			spriteWidth, tileHeight,
+		)
+	}
+
+	return []byte(b.String())
+}
+
+func vttTime(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000,
+	)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVTTTime(t *testing.T) {
+	for _, tt := range []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00.000"},
+		{1.5, "00:00:01.500"},
+		{59.9996, "00:01:00.000"},
+		{61.25, "00:01:01.250"},
+		{3599.999, "00:59:59.999"},
+		{3723.004, "01:02:03.004"},
+		{36000, "10:00:00.000"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := vttTime(tt.seconds); got != tt.want {
+				t.Errorf("vttTime(%g) %s, want %s", tt.seconds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpritesIndex(t *testing.T) {
+	// 205 frames of 2s over 409.5s: two full sheets and 5 frames on a third,
+	// tiles of 160x90.
+	index := string(spritesIndex(205, 2, 409.5, 90))
+
+	header, body, _ := strings.Cut(index, "\n\n")
+	if header != "WEBVTT" {
+		t.Fatalf("header %q, want WEBVTT", header)
+	}
+	cues := strings.Split(strings.TrimSuffix(body, "\n"), "\n\n")
+	if len(cues) != 205 {
+		t.Fatalf("%d cues, want 205", len(cues))
+	}
+
+	for _, tt := range []struct {
+		frame int
+		want  string
+	}{
+		{0, "00:00:00.000 --> 00:00:02.000\nsprites/1#xywh=0,0,160,90"},
+		{1, "00:00:02.000 --> 00:00:04.000\nsprites/1#xywh=160,0,160,90"},
+		{9, "00:00:18.000 --> 00:00:20.000\nsprites/1#xywh=1440,0,160,90"},
+		{10, "00:00:20.000 --> 00:00:22.000\nsprites/1#xywh=0,90,160,90"},
+		{99, "00:03:18.000 --> 00:03:20.000\nsprites/1#xywh=1440,810,160,90"},
+		{100, "00:03:20.000 --> 00:03:22.000\nsprites/2#xywh=0,0,160,90"},
+		{200, "00:06:40.000 --> 00:06:42.000\nsprites/3#xywh=0,0,160,90"},
+		// The last cue ends with the video.
+		{204, "00:06:48.000 --> 00:06:49.500\nsprites/3#xywh=640,0,160,90"},
+	} {
+		if cues[tt.frame] != tt.want {
+			t.Errorf("cue %d\n%s\nwant\n%s", tt.frame, cues[tt.frame], tt.want)
+		}
+	}
+}
@@ -7,15 +7,42 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"time"
 
+	"github.com/tekig/photo-backup-server/internal/entity"
 	"github.com/tekig/photo-backup-server/internal/repository"
 )
 
+const (
+	// defaultICCProfile is shipped by colord-data.
+	defaultICCProfile     = "/usr/share/color/icc/colord/sRGB.icc"
+	defaultSpriteInterval = 10 * time.Second
+)
+
+type CMDConfig struct {
+	// ICCProfile is the sRGB profile thumbnails are converted to.
+	ICCProfile string
+	// SpriteInterval is the video time between two scrubbing sprites.
+	SpriteInterval time.Duration
+}
+
 type CMD struct {
+	iccProfile     string
+	spriteInterval time.Duration
 }
 
-func New() *CMD {
-	return &CMD{}
+func New(c CMDConfig) *CMD {
+	if c.ICCProfile == "" {
+		c.ICCProfile = defaultICCProfile
+	}
+	if c.SpriteInterval <= 0 {
+		c.SpriteInterval = defaultSpriteInterval
+	}
+
+	return &CMD{
+		iccProfile:     c.ICCProfile,
+		spriteInterval: c.SpriteInterval,
+	}
 }
 
 func (c *CMD) Create(ctx context.Context, original repository.Object) (*repository.Object, error) {
@@ -25,16 +52,24 @@ func (c *CMD) Create(ctx context.Context, original repository.Object) (*reposito
 	case strings.HasPrefix(original.ContentType, "video/") || original.ContentType == "image/gif":
 		preview := path.Join(dir, name+".mp4")
 
+		info, err := probeVideo(ctx, original.Path)
+		if err != nil {
+			return nil, fmt.Errorf("probe video: %w", err)
+		}
+
 		if err := cmd(
 			ctx, "ffmpeg",
+			"-noautorotate",
 			"-i", original.Path,
 			"-t", "3",
 			"-an",
-			"-vf", `scale='if(gt(iw,ih),-1,256)':'if(gt(iw,ih),256,-1)',scale=trunc(iw/2)*2:trunc(ih/2)*2`,
+			"-map_metadata", "-1",
+			"-vf", info.filter(previewScale),
 			preview,
 		); err != nil {
 			return nil, fmt.Errorf("ffmpeg convert: %w", err)
 		}
+		thumbnailSources.Add(sourceVideo, 1)
 
 		return &repository.Object{
 			Path:        preview,
@@ -43,23 +78,33 @@ func (c *CMD) Create(ctx context.Context, original repository.Object) (*reposito
 	case strings.HasPrefix(original.ContentType, "image/"):
 		preview := path.Join(dir, name+".jpg")
 
-		// ffmpeg -i temp.jpg -vf "scale='if(gt(iw,ih),-1,256)':'if(gt(iw,ih),256,-1)'" -q:v 2 output.jpg
-		// magick temp.jpg -resize 256x256^ output.jpg
-		if err := cmd(
-			ctx, "magick",
-			original.Path,
+		source, err := imageSource(ctx, original.Path, original.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("image source: %w", err)
+		}
+
+		// Orientation is applied before stripping the EXIF it is stored in,
+		// the embedded profile is converted to sRGB so wide gamut images
+		// keep their colors in browsers ignoring untagged JPEG profiles.
+		args := append(source.args(),
+			"-auto-orient",
+			"-profile", c.iccProfile,
 			"-resize", "256x256^",
+			"-strip",
 			preview,
-		); err != nil {
+		)
+
+		if err := cmd(ctx, "magick", args...); err != nil {
 			return nil, fmt.Errorf("magick convert: %w", err)
 		}
+		thumbnailSources.Add(source.route, 1)
 
 		return &repository.Object{
 			Path:        preview,
 			ContentType: "image/jpeg",
 		}, nil
 	default:
-		return nil, fmt.Errorf("not support content type `%s`", original.ContentType)
+		return nil, fmt.Errorf("content type `%s`: %w", original.ContentType, entity.ErrUnsupportedMedia)
 	}
 }
 
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+// newTestCMD skips unless the tools and the sRGB profile of the image are
+// installed.
+func newTestCMD(t *testing.T) *CMD {
+	t.Helper()
+
+	for _, prog := range []string{"magick", "exiftool"} {
+		if _, err := exec.LookPath(prog); err != nil {
+			t.Skipf("%s not in PATH", prog)
+		}
+	}
+	if _, err := os.Stat(defaultICCProfile); err != nil {
+		t.Skipf("sRGB profile: %s", err)
+	}
+
+	return New(CMDConfig{})
+}
+
+// createThumbnail copies the fixture to a temp dir, thumbnails are written
+// next to their original.
+func createThumbnail(t *testing.T, c *CMD, fixture string) image.Image {
+	t.Helper()
+
+	data, err := os.ReadFile(path.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := path.Join(t.TempDir(), fixture)
+	if err := os.WriteFile(original, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	th, err := c.Create(context.Background(), repository.Object{
+		Path:        original,
+		ContentType: "image/jpeg",
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	out, err := os.ReadFile(th.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif\x00\x00")) {
+		t.Error("thumbnail keeps EXIF")
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode thumbnail: %s", err)
+	}
+
+	return img
+}
+
+func rgb(img image.Image, x, y int) [3]int {
+	r, g, b, _ := img.At(x, y).RGBA()
+
+	return [3]int{int(r >> 8), int(g >> 8), int(b >> 8)}
+}
+
+func near(got, want [3]int, tolerance int) bool {
+	for i := range got {
+		if d := got[i] - want[i]; d > tolerance || d < -tolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The fixtures are 64x32 with a red left half and a blue right half.
+func TestCreateOrientation(t *testing.T) {
+	c := newTestCMD(t)
+
+	for _, tt := range []struct {
+		fixture string
+		top     [3]int
+	}{
+		// 90° clockwise, the left half ends up on top.
+		{"orientation-6.jpg", [3]int{255, 0, 0}},
+		// 90° counterclockwise, the right half ends up on top.
+		{"orientation-8.jpg", [3]int{0, 0, 255}},
+	} {
+		t.Run(tt.fixture, func(t *testing.T) {
+			img := createThumbnail(t, c, tt.fixture)
+
+			b := img.Bounds()
+			if b.Dx() >= b.Dy() {
+				t.Fatalf("size %dx%d, want portrait", b.Dx(), b.Dy())
+			}
+			if got := rgb(img, b.Dx()/2, b.Dy()/8); !near(got, tt.top, 24) {
+				t.Errorf("top %v, want %v", got, tt.top)
+			}
+		})
+	}
+}
+
+// The fixture is P3 (200, 80, 80), the same color is (216, 69, 75) in sRGB.
+// Assigning sRGB instead of converting keeps the numbers.
+func TestCreateDisplayP3(t *testing.T) {
+	c := newTestCMD(t)
+
+	img := createThumbnail(t, c, "display-p3.jpg")
+
+	b := img.Bounds()
+	if got, want := rgb(img, b.Dx()/2, b.Dy()/2), [3]int{216, 69, 75}; !near(got, want, 6) {
+		t.Errorf("color %v, want %v", got, want)
+	}
+}
+
+func TestCreateStripsGPS(t *testing.T) {
+	c := newTestCMD(t)
+
+	// createThumbnail fails on any EXIF left, GPS included.
+	createThumbnail(t, c, "gps.jpg")
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+	"github.com/tekig/photo-backup-server/internal/repository"
+)
+
+// previewScale fits the shortest side to 256px keeping even dimensions for
+// H.264.
+const previewScale = `scale='if(gt(iw,ih),-1,256)':'if(gt(iw,ih),256,-1)',scale=trunc(iw/2)*2:trunc(ih/2)*2`
+
+// hdrTransfers are the PQ (HDR10, Dolby Vision) and HLG transfer
+// characteristics as reported by ffprobe.
+var hdrTransfers = map[string]bool{
+	"smpte2084":    true,
+	"arib-std-b67": true,
+}
+
+// toneMap converts HDR to SDR BT.709: linearize, map the highlights with
+// hable and go back to the BT.709 transfer.
+const toneMap = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv"
+
+// posterDarkness is the average luma, out of 255, below which frames are
+// skipped as black or fading.
+const posterDarkness = 32
+
+// posterWindow is how many seconds from the start are searched for a poster.
+const posterWindow = 30
+
+// Poster picks a representative frame, skipping dark ones, from the start of
+// a video or animated image.
+func (c *CMD) Poster(ctx context.Context, original repository.Object) (*repository.Object, error) {
+	if !strings.HasPrefix(original.ContentType, "video/") && original.ContentType != "image/gif" {
+		return nil, fmt.Errorf("content type `%s`: %w", original.ContentType, entity.ErrUnsupportedMedia)
+	}
+
+	dir, name := path.Split(original.Path)
+	poster := path.Join(dir, name+".poster.jpg")
+
+	info, err := probeVideo(ctx, original.Path)
+	if err != nil {
+		return nil, fmt.Errorf("probe video: %w", err)
+	}
+
+	// Frames are measured after tone mapping and the 8-bit conversion so that
+	// the darkness holds for HDR and 10-bit videos. A video dark all along has
+	// every frame dropped, the plain thumbnail filter is the fallback.
+	for _, selects := range [][]string{
+		{"signalstats", fmt.Sprintf("metadata=mode=select:key=lavfi.signalstats.YAVG:value=%d:function=greater", posterDarkness), "thumbnail=100"},
+		{"thumbnail=100"},
+	} {
+		err = cmd(
+			ctx, "ffmpeg",
+			"-y",
+			"-noautorotate",
+			"-t", fmt.Sprint(posterWindow),
+			"-i", original.Path,
+			"-an",
+			"-map_metadata", "-1",
+			"-vf", strings.Join(append([]string{info.filter(previewScale)}, selects...), ","),
+			"-frames:v", "1",
+			poster,
+		)
+		if err != nil {
+			continue
+		}
+
+		if stat, err := os.Stat(poster); err == nil && stat.Size() != 0 {
+			return &repository.Object{
+				Path:        poster,
+				ContentType: "image/jpeg",
+			}, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg poster: %w", err)
+	}
+
+	return nil, fmt.Errorf("ffmpeg poster: no frame: %w", entity.ErrUnsupportedMedia)
+}
+
+type videoInfo struct {
+	Width    int
+	Height   int
+	Duration float64
+	Transfer string
+	// Rotation is clockwise in degrees, 0, 90, 180 or 270.
+	Rotation int
+	Audio    bool
+}
+
+type ffprobeOutput struct {
+	Streams []ffprobeStream `json:"streams"`
+	Format  struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+type ffprobeStream struct {
+	CodecType     string            `json:"codec_type"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	ColorTransfer string            `json:"color_transfer"`
+	Duration      string            `json:"duration"`
+	Tags          map[string]string `json:"tags"`
+	SideDataList  []struct {
+		Rotation float64 `json:"rotation"`
+	} `json:"side_data_list"`
+}
+
+func probeVideo(ctx context.Context, file string) (*videoInfo, error) {
+	cmd := exec.CommandContext(
+		ctx, "ffprobe",
+		"-v", "error",
+		"-show_streams",
+		"-show_format",
+		"-of", "json",
+		file,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run stderr=`%s`: %w", stderr.String(), err)
+	}
+
+	var output ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	video := slices.IndexFunc(output.Streams, func(s ffprobeStream) bool { return s.CodecType == "video" })
+	if video == -1 {
+		return nil, fmt.Errorf("no video stream: %w", entity.ErrUnsupportedMedia)
+	}
+
+	stream := output.Streams[video]
+	info := &videoInfo{
+		Width:    stream.Width,
+		Height:   stream.Height,
+		Transfer: stream.ColorTransfer,
+		Audio:    slices.ContainsFunc(output.Streams, func(s ffprobeStream) bool { return s.CodecType == "audio" }),
+	}
+
+	for _, v := range []string{stream.Duration, output.Format.Duration} {
+		if d, err := strconv.ParseFloat(v, 64); err == nil {
+			info.Duration = d
+			break
+		}
+	}
+
+	// The display matrix rotation is counterclockwise, the legacy rotate tag
+	// clockwise.
+	var rotation float64
+	if v, ok := stream.Tags["rotate"]; ok {
+		rotation, _ = strconv.ParseFloat(v, 64)
+	} else {
+		for _, side := range stream.SideDataList {
+			if side.Rotation != 0 {
+				rotation = -side.Rotation
+			}
+		}
+	}
+	info.Rotation = (int(math.Round(rotation/90))*90%360 + 360) % 360
+
+	return info, nil
+}
+
+// hdr reports whether the video needs tone mapping.
+func (v *videoInfo) hdr() bool {
+	return hdrTransfers[v.Transfer]
+}
+
+// filter is the video filter chain for previews: rotation, the given scale
+// filters, tone mapping of HDR and 8-bit 4:2:0 output. Run ffmpeg with
+// -noautorotate as rotation is part of the chain.
+func (v *videoInfo) filter(scale ...string) string {
+	var filters []string
+
+	switch v.Rotation {
+	case 90:
+		filters = append(filters, "transpose=clock")
+	case 180:
+		filters = append(filters, "hflip", "vflip")
+	case 270:
+		filters = append(filters, "transpose=cclock")
+	}
+
+	filters = append(filters, scale...)
+
+	if v.hdr() {
+		filters = append(filters, toneMap)
+	}
+
+	filters = append(filters, "format=yuv420p")
+
+	return strings.Join(filters, ",")
+}
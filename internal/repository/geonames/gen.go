@@ -0,0 +1,159 @@
+//go:build ignore
+
+// gen builds cities.tsv, the place list embedded for servers without a
+// GeoNames dump, from the GeoNames cities with a population over 15000 and
+// the names of their regions and countries.
+//
+//	go generate ./internal/repository/geonames
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const dump = "https://download.geonames.org/export/dump/"
+
+const header = `# Cities with a population over 15000 from GeoNames, https://www.geonames.org,
+# licensed under CC BY 4.0, https://creativecommons.org/licenses/by/4.0/.
+# Generated by gen.go from cities15000, admin1CodesASCII and countryInfo.
+# name, region, country, latitude, longitude
+`
+
+type city struct {
+	name, region, country, latitude, longitude string
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	admin1, err := names("admin1CodesASCII.txt", 0, 1)
+	if err != nil {
+		return fmt.Errorf("admin1: %w", err)
+	}
+
+	countries, err := names("countryInfo.txt", 0, 4)
+	if err != nil {
+		return fmt.Errorf("countries: %w", err)
+	}
+
+	data, err := download("cities15000.zip")
+	if err != nil {
+		return fmt.Errorf("cities: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("unzip cities: %w", err)
+	}
+
+	f, err := archive.Open("cities15000.txt")
+	if err != nil {
+		return fmt.Errorf("open cities: %w", err)
+	}
+	defer f.Close()
+
+	// The geoname table: name is the 2nd column, coordinates the 5th and 6th,
+	// country code the 9th and admin1 code the 11th.
+	var cities []city
+	err = scan(f, func(fields []string) error {
+		if len(fields) < 11 {
+			return fmt.Errorf("want 11 fields, got %d", len(fields))
+		}
+
+		cities = append(cities, city{
+			name:      fields[1],
+			region:    admin1[fields[8]+"."+fields[10]],
+			country:   cmp.Or(countries[fields[8]], fields[8]),
+			latitude:  fields[4],
+			longitude: fields[5],
+		})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("parse cities: %w", err)
+	}
+
+	slices.SortFunc(cities, func(a, b city) int {
+		return cmp.Or(
+			cmp.Compare(a.country, b.country),
+			cmp.Compare(a.region, b.region),
+			cmp.Compare(a.name, b.name),
+		)
+	})
+
+	var out bytes.Buffer
+	out.WriteString(header)
+	for _, c := range cities {
+		fmt.Fprintf(&out, "%s\t%s\t%s\t%s\t%s\n", c.name, c.region, c.country, c.latitude, c.longitude)
+	}
+
+	return os.WriteFile("cities.tsv", out.Bytes(), 0o644)
+}
+
+func download(name string) ([]byte, error) {
+	res, err := http.Get(dump + name)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get `%s`: %s", name, res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// names reads a code to name table such as admin1CodesASCII.txt.
+func names(name string, code, value int) (map[string]string, error) {
+	data, err := download(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var names = make(map[string]string)
+	err = scan(bytes.NewReader(data), func(fields []string) error {
+		if len(fields) <= max(code, value) {
+			return fmt.Errorf("want %d fields, got %d", max(code, value)+1, len(fields))
+		}
+		names[fields[code]] = fields[value]
+
+		return nil
+	})
+
+	return names, err
+}
+
+// scan calls fn for every line skipping blank lines and `#` comments.
+func scan(r io.Reader, fn func(fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := fn(strings.Split(line, "\t")); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+
+	return scanner.Err()
+}
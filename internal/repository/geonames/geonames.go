@@ -0,0 +1,235 @@
+package geonames
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+const earthRadius = 6371.0
+
+// maxDistance in kilometers, locations farther from any city have no place.
+const maxDistance = 50.0
+
+// cities is used when no GeoNames dump is configured, one `name, region,
+// country, latitude, longitude` per line. gen.go builds it from the GeoNames
+// cities15000 dump, CC BY 4.0.
+//
+//go:generate go run gen.go
+//go:embed cities.tsv
+var cities []byte
+
+type GeoNamesConfig struct {
+	// Cities is a GeoNames dump such as cities15000.txt.
+	Cities string
+	// Admin1 is admin1CodesASCII.txt, region names are empty without it.
+	Admin1 string
+	// Countries is countryInfo.txt, countries are ISO codes without it.
+	Countries string
+}
+
+// GeoNames reverse geocodes locations to the nearest city.
+type GeoNames struct {
+	tree *tree
+}
+
+func New(c GeoNamesConfig) (*GeoNames, error) {
+	if c.Cities == "" {
+		places, err := parseEmbedded(bytes.NewReader(cities))
+		if err != nil {
+			return nil, fmt.Errorf("parse embedded: %w", err)
+		}
+
+		return &GeoNames{
+			tree: newTree(places),
+		}, nil
+	}
+
+	var admin1, countries = map[string]string{}, map[string]string{}
+	if c.Admin1 != "" {
+		var err error
+		if admin1, err = parseFile(c.Admin1, parseNames(0, 1)); err != nil {
+			return nil, fmt.Errorf("parse admin1: %w", err)
+		}
+	}
+	if c.Countries != "" {
+		var err error
+		if countries, err = parseFile(c.Countries, parseNames(0, 4)); err != nil {
+			return nil, fmt.Errorf("parse countries: %w", err)
+		}
+	}
+
+	places, err := parseFile(c.Cities, func(r io.Reader) ([]place, error) {
+		return parseCities(r, admin1, countries)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse cities: %w", err)
+	}
+
+	fmt.Printf("Loaded %d places from `%s`\n", len(places), c.Cities)
+
+	return &GeoNames{
+		tree: newTree(places),
+	}, nil
+}
+
+// Reverse returns the nearest city, entity.ErrNotFound when there is none
+// within maxDistance.
+func (g *GeoNames) Reverse(ctx context.Context, l entity.Location) (*entity.Place, error) {
+	p, chord := g.tree.nearest(toPoint(l))
+	if p == nil || chordDistance(chord) > maxDistance {
+		return nil, fmt.Errorf("nearest city: %w", entity.ErrNotFound)
+	}
+
+	found := p.Place
+
+	return &found, nil
+}
+
+type place struct {
+	entity.Place
+	point point
+}
+
+func parseFile[T any](name string, parse func(r io.Reader) (T, error)) (T, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	return parse(f)
+}
+
+func parseEmbedded(r io.Reader) ([]place, error) {
+	var places []place
+	err := scanTSV(r, func(fields []string) error {
+		if len(fields) < 5 {
+			return fmt.Errorf("want 5 fields, got %d", len(fields))
+		}
+
+		l, err := parseLocation(fields[3], fields[4])
+		if err != nil {
+			return err
+		}
+
+		places = append(places, place{
+			Place: entity.Place{
+				City:    fields[0],
+				Region:  fields[1],
+				Country: fields[2],
+			},
+			point: toPoint(l),
+		})
+
+		return nil
+	})
+
+	return places, err
+}
+
+// parseCities reads the GeoNames geoname table: name is the 2nd column,
+// coordinates the 5th and 6th, country code the 9th and admin1 code the 11th.
+func parseCities(r io.Reader, admin1, countries map[string]string) ([]place, error) {
+	var places []place
+	err := scanTSV(r, func(fields []string) error {
+		if len(fields) < 11 {
+			return fmt.Errorf("want 11 fields, got %d", len(fields))
+		}
+
+		l, err := parseLocation(fields[4], fields[5])
+		if err != nil {
+			return err
+		}
+
+		country := fields[8]
+		if name, ok := countries[country]; ok {
+			country = name
+		}
+
+		places = append(places, place{
+			Place: entity.Place{
+				City:    fields[1],
+				Region:  admin1[fields[8]+"."+fields[10]],
+				Country: country,
+			},
+			point: toPoint(l),
+		})
+
+		return nil
+	})
+
+	return places, err
+}
+
+// parseNames reads a code to name table such as admin1CodesASCII.txt.
+func parseNames(code, name int) func(r io.Reader) (map[string]string, error) {
+	return func(r io.Reader) (map[string]string, error) {
+		var names = make(map[string]string)
+		err := scanTSV(r, func(fields []string) error {
+			if len(fields) <= max(code, name) {
+				return fmt.Errorf("want %d fields, got %d", max(code, name)+1, len(fields))
+			}
+			names[fields[code]] = fields[name]
+
+			return nil
+		})
+
+		return names, err
+	}
+}
+
+// scanTSV calls fn for every line skipping blank lines and `#` comments.
+func scanTSV(r io.Reader, fn func(fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := fn(strings.Split(line, "\t")); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	return nil
+}
+
+func parseLocation(lat, lon string) (entity.Location, error) {
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return entity.Location{}, fmt.Errorf("parse latitude: %w", err)
+	}
+
+	longitude, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return entity.Location{}, fmt.Errorf("parse longitude: %w", err)
+	}
+
+	return entity.Location{
+		Latitude:  latitude,
+		Longitude: longitude,
+	}, nil
+}
+
+// chordDistance converts the squared chord between unit vectors to the great
+// circle distance in kilometers.
+func chordDistance(chord float64) float64 {
+	return 2 * math.Asin(math.Min(1, math.Sqrt(chord)/2)) * earthRadius
+}
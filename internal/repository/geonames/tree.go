@@ -0,0 +1,103 @@
+package geonames
+
+import (
+	"math"
+	"slices"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// point is a location on the unit sphere, so that the euclidean distance
+// grows with the great circle distance and the antimeridian needs no care.
+type point [3]float64
+
+func toPoint(l entity.Location) point {
+	lat, lon := l.Latitude*math.Pi/180, l.Longitude*math.Pi/180
+
+	return point{
+		math.Cos(lat) * math.Cos(lon),
+		math.Cos(lat) * math.Sin(lon),
+		math.Sin(lat),
+	}
+}
+
+func (p point) distance(o point) float64 {
+	var d float64
+	for i := range p {
+		d += (p[i] - o[i]) * (p[i] - o[i])
+	}
+
+	return d
+}
+
+// tree is a static k-d tree stored in place: the median of every range is
+// its root, split on the axis of the depth.
+type tree struct {
+	places []place
+}
+
+func newTree(places []place) *tree {
+	t := &tree{places: places}
+	t.build(0, len(places), 0)
+
+	return t
+}
+
+func (t *tree) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+
+	axis := depth % 3
+	slices.SortFunc(t.places[lo:hi], func(a, b place) int {
+		switch {
+		case a.point[axis] < b.point[axis]:
+			return -1
+		case a.point[axis] > b.point[axis]:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	mid := (lo + hi) / 2
+	t.build(lo, mid, depth+1)
+	t.build(mid+1, hi, depth+1)
+}
+
+// nearest returns the closest place and its squared chord distance.
+func (t *tree) nearest(p point) (*place, float64) {
+	var (
+		best     *place
+		bestDist = math.Inf(1)
+	)
+
+	var search func(lo, hi, depth int)
+	search = func(lo, hi, depth int) {
+		if lo >= hi {
+			return
+		}
+
+		mid := (lo + hi) / 2
+		node := &t.places[mid]
+		if d := node.point.distance(p); d < bestDist {
+			best, bestDist = node, d
+		}
+
+		axis := depth % 3
+		diff := p[axis] - node.point[axis]
+
+		near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+		if diff > 0 {
+			near, far = far, near
+		}
+
+		search(near[0], near[1], depth+1)
+		if diff*diff < bestDist {
+			search(far[0], far[1], depth+1)
+		}
+	}
+	search(0, len(t.places), 0)
+
+	return best, bestDist
+}
@@ -0,0 +1,110 @@
+package geonames
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+func testPlaces() []place {
+	var places []place
+	for _, c := range []struct {
+		city                string
+		latitude, longitude float64
+	}{
+		{"Lisbon", 38.72, -9.14},
+		{"Porto", 41.15, -8.61},
+		{"Suva", -18.14, 178.44},
+		{"Somosomo", -16.77, 179.97},
+		{"Apia", -13.83, -171.76},
+		{"Reykjavik", 64.14, -21.94},
+		{"Longyearbyen", 78.22, 15.65},
+		{"McMurdo", -77.85, 166.67},
+	} {
+		places = append(places, place{
+			Place: entity.Place{City: c.city},
+			point: toPoint(entity.Location{Latitude: c.latitude, Longitude: c.longitude}),
+		})
+	}
+
+	return places
+}
+
+func TestReverse(t *testing.T) {
+	g := &GeoNames{tree: newTree(testPlaces())}
+
+	for _, tt := range []struct {
+		name     string
+		location entity.Location
+		city     string
+	}{
+		{"exact", entity.Location{Latitude: 38.72, Longitude: -9.14}, "Lisbon"},
+		{"near", entity.Location{Latitude: 38.80, Longitude: -9.30}, "Lisbon"},
+		{"closer to porto", entity.Location{Latitude: 40.90, Longitude: -8.60}, "Porto"},
+		// Somosomo is across the antimeridian, Apia on the same side.
+		{"antimeridian", entity.Location{Latitude: -16.80, Longitude: -179.90}, "Somosomo"},
+		{"too far", entity.Location{Latitude: 0, Longitude: -30}, ""},
+		{"north pole", entity.Location{Latitude: 90, Longitude: 0}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			place, err := g.Reverse(context.Background(), tt.location)
+			if tt.city == "" {
+				if !errors.Is(err, entity.ErrNotFound) {
+					t.Fatalf("err %v, want %v", err, entity.ErrNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if place.City != tt.city {
+				t.Errorf("city %s, want %s", place.City, tt.city)
+			}
+		})
+	}
+}
+
+// The tree must agree with a linear scan, ties aside.
+func TestNearest(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+
+	random := func() point {
+		return toPoint(entity.Location{
+			Latitude:  r.Float64()*180 - 90,
+			Longitude: r.Float64()*360 - 180,
+		})
+	}
+
+	for _, n := range []int{0, 1, 2, 3, 100, 1000} {
+		places := make([]place, n)
+		for i := range places {
+			places[i].point = random()
+		}
+		tree := newTree(slices.Clone(places))
+
+		for range 200 {
+			p := random()
+
+			want := math.Inf(1)
+			for _, o := range places {
+				want = min(want, o.point.distance(p))
+			}
+
+			got, dist := tree.nearest(p)
+			if n == 0 {
+				if got != nil {
+					t.Fatalf("%d places: found %v", n, got)
+				}
+				continue
+			}
+			if dist != want || got.point.distance(p) != dist {
+				t.Fatalf("%d places: distance %g, want %g", n, dist, want)
+			}
+		}
+	}
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"io"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
 )
 
 type Object struct {
@@ -27,13 +29,53 @@ type ObjectResponse struct {
 	Content       io.ReadCloser
 }
 
+type ObjectInfo struct {
+	ContentLength int64
+	ContentType   string
+}
+
 type Storage interface {
 	Download(ctx context.Context, req ObjectRequest) (*ObjectResponse, error)
+	Stat(ctx context.Context, path string) (*ObjectInfo, error)
 	Upload(ctx context.Context, object ObjectReader) error
 	Move(ctx context.Context, src, dst string) error
 	Delete(ctx context.Context, path string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Sprites are tiled frames of a video and a WebVTT index mapping time ranges
+// to tiles of the sheets.
+type Sprites struct {
+	Index  Object
+	Sheets []Object
 }
 
 type Thumbnail interface {
 	Create(ctx context.Context, object Object) (*Object, error)
+	// Poster creates a still image of a video, entity.ErrUnsupportedMedia
+	// for other content.
+	Poster(ctx context.Context, object Object) (*Object, error)
+	// Sprites creates scrubbing sprites of a video, entity.ErrUnsupportedMedia
+	// for other content and videos too short to scrub.
+	Sprites(ctx context.Context, object Object) (*Sprites, error)
+	// Display creates an image browsers can render of originals they cannot,
+	// entity.ErrUnsupportedMedia for videos and web images.
+	Display(ctx context.Context, object Object) (*Object, error)
+}
+
+type Transcoder interface {
+	// HLS transcodes a video into an HLS stream written to dir and returns
+	// the master playlist, the other files of the stream are next to it.
+	HLS(ctx context.Context, object Object, dir string) (*Object, error)
+}
+
+type Metadata interface {
+	Extract(ctx context.Context, object Object) (*entity.Metadata, error)
+	// Motion extracts the video embedded in a motion photo, entity.ErrNotFound
+	// when there is none.
+	Motion(ctx context.Context, object Object) (*Object, error)
+}
+
+type Geocoder interface {
+	Reverse(ctx context.Context, location entity.Location) (*entity.Place, error)
 }
@@ -2,12 +2,16 @@ package s3
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"path"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -46,20 +50,13 @@ func New(c StorageConfig) (*Storage, error) {
 }
 
 func (s *Storage) Download(ctx context.Context, req repository.ObjectRequest) (*repository.ObjectResponse, error) {
-	output, err := s3.New(s.s).GetObject(&s3.GetObjectInput{
+	output, err := s3.New(s.s).GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: &s.bucket,
 		Key:    &req.Path,
 		Range:  req.Range,
 	})
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchKey:
-				return nil, fmt.Errorf("get object: %w: %w", entity.ErrNotFound, err)
-			default:
-				return nil, fmt.Errorf("get object: %w", err)
-			}
-		}
+		return nil, fmt.Errorf("get object: %w", toError(err))
 	}
 
 	return &repository.ObjectResponse{
@@ -69,6 +66,21 @@ func (s *Storage) Download(ctx context.Context, req repository.ObjectRequest) (*
 	}, nil
 }
 
+func (s *Storage) Stat(ctx context.Context, path string) (*repository.ObjectInfo, error) {
+	output, err := s3.New(s.s).HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: &s.bucket,
+		Key:    &path,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("head object: %w", toError(err))
+	}
+
+	return &repository.ObjectInfo{
+		ContentLength: aws.Int64Value(output.ContentLength),
+		ContentType:   aws.StringValue(output.ContentType),
+	}, nil
+}
+
 func (s *Storage) Upload(ctx context.Context, object repository.ObjectReader) error {
 	_, err := s3manager.NewUploader(s.s).UploadWithContext(ctx, &s3manager.UploadInput{
 		Body:        object.Content,
@@ -77,11 +89,12 @@ func (s *Storage) Upload(ctx context.Context, object repository.ObjectReader) er
 		Key:         &object.Path,
 	})
 	if err != nil {
-		return fmt.Errorf("upload: %w", err)
+		return fmt.Errorf("upload: %w", toError(err))
 	}
 
 	return nil
 }
+
 func (s *Storage) Move(ctx context.Context, src, dst string) error {
 	svc := s3.New(s.s)
 
@@ -90,21 +103,21 @@ func (s *Storage) Move(ctx context.Context, src, dst string) error {
 		CopySource: aws.String(path.Join(s.bucket, src)),
 		Key:        &dst,
 	}); err != nil {
-		return fmt.Errorf("copy: %w", err)
+		return fmt.Errorf("copy: %w", toError(err))
 	}
 
 	if err := svc.WaitUntilObjectExistsWithContext(ctx, &s3.HeadObjectInput{
 		Bucket: &s.bucket,
 		Key:    &dst,
 	}); err != nil {
-		return fmt.Errorf("wait until exists: %w", err)
+		return fmt.Errorf("wait until exists: %w", toError(err))
 	}
 
 	if _, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Bucket: &s.bucket,
 		Key:    &src,
 	}); err != nil {
-		return fmt.Errorf("delete: %w", err)
+		return fmt.Errorf("delete: %w", toError(err))
 	}
 
 	return nil
@@ -115,8 +128,95 @@ func (s *Storage) Delete(ctx context.Context, path string) error {
 		Bucket: &s.bucket,
 		Key:    &path,
 	}); err != nil {
-		return fmt.Errorf("delete: %w", err)
+		return fmt.Errorf("delete: %w", toError(err))
 	}
 
 	return nil
 }
+
+// DeletePrefix deletes every object whose key starts with prefix.
+func (s *Storage) DeletePrefix(ctx context.Context, prefix string) error {
+	svc := s3.New(s.s)
+
+	var deleteErr error
+	if err := svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &prefix,
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		if len(page.Contents) == 0 {
+			return true
+		}
+
+		var objects = make([]*s3.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+		}
+
+		// A page holds at most 1000 keys, the limit of one DeleteObjects.
+		if _, err := svc.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: &s.bucket,
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		}); err != nil {
+			deleteErr = fmt.Errorf("delete objects: %w", toError(err))
+			return false
+		}
+
+		return true
+	}); err != nil {
+		return fmt.Errorf("list objects: %w", toError(err))
+	}
+
+	return deleteErr
+}
+
+// toError classifies S3 failures into domain errors. The storage is
+// unavailable on timeouts, 5xx and throttling, other failures are internal.
+func toError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return fmt.Errorf("%w: %w", entity.ErrNotFound, err)
+		case "InvalidRange":
+			return fmt.Errorf("%w: %w", entity.ErrRangeNotSatisfiable, err)
+		}
+	}
+
+	if unavailable(err) {
+		return fmt.Errorf("%w: %w", entity.ErrUpstreamUnavailable, err)
+	}
+
+	return err
+}
+
+// unavailable reports whether S3 failed on its side, throttled or did not
+// answer in time. SDK errors keep their cause in OrigErr, not Unwrap.
+func unavailable(err error) bool {
+	for err != nil {
+		var nerr net.Error
+		if errors.As(err, &nerr) && nerr.Timeout() || errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+
+		aerr, ok := err.(awserr.Error)
+		if !ok {
+			return false
+		}
+		if rerr, ok := err.(awserr.RequestFailure); ok && rerr.StatusCode() >= http.StatusInternalServerError {
+			return true
+		}
+		switch aerr.Code() {
+		case "SlowDown", "RequestTimeout", request.ErrCodeResponseTimeout:
+			return true
+		}
+		if request.IsErrorThrottle(err) {
+			return true
+		}
+
+		err = aerr.OrigErr()
+	}
+
+	return false
+}
@@ -0,0 +1,372 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+// Fields of a document are searched with `field:value`, every token is also
+// searchable as free text.
+const (
+	FieldName    = "name"
+	FieldCaption = "caption"
+	FieldTag     = "tag"
+	FieldCamera  = "camera"
+	FieldType    = "type"
+	FieldPlace   = "place"
+)
+
+const (
+	keySeparator = "\x1f"
+	dateRange    = ".."
+)
+
+type Document struct {
+	ID     string
+	Fields map[string][]string
+	// Time is the capture time used by date filters.
+	Time int64
+}
+
+// Index is an in-memory inverted index with prefix matching.
+type Index struct {
+	mu       sync.Mutex
+	postings map[string]map[string]struct{}
+	docs     map[string]document
+	// keys are the sorted posting keys for prefix lookups, nil when stale.
+	keys []string
+}
+
+type document struct {
+	keys []string
+	time int64
+}
+
+func New() *Index {
+	return &Index{
+		postings: make(map[string]map[string]struct{}),
+		docs:     make(map[string]document),
+	}
+}
+
+// Put indexes the document, replacing a previous version with the same ID.
+func (i *Index) Put(doc Document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.delete(doc.ID)
+
+	var keys []string
+	for field, values := range doc.Fields {
+		for _, value := range values {
+			for _, token := range Tokenize(value) {
+				keys = append(keys, key(field, token), key("", token))
+			}
+		}
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	for _, k := range keys {
+		ids, ok := i.postings[k]
+		if !ok {
+			ids = make(map[string]struct{})
+			i.postings[k] = ids
+			i.keys = nil
+		}
+		ids[doc.ID] = struct{}{}
+	}
+
+	i.docs[doc.ID] = document{
+		keys: keys,
+		time: doc.Time,
+	}
+}
+
+func (i *Index) Delete(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.delete(id)
+}
+
+func (i *Index) delete(id string) {
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, k := range doc.keys {
+		delete(i.postings[k], id)
+		if len(i.postings[k]) == 0 {
+			delete(i.postings, k)
+			i.keys = nil
+		}
+	}
+	delete(i.docs, id)
+}
+
+// Search returns IDs of documents matching every term of the query.
+//
+// Terms are free text (`lisbon`), field queries (`camera:canon`, `tag:"new
+// year"`, `place:portugal`), prefixes (`beach*`, `camera:can*`), negations (`-tag:work`) and
+// capture dates (`date:2023`, `date:2023-05..2023-08`, `after:2023-01-01`,
+// `before:2024-01-01`).
+func (i *Index) Search(query string) ([]string, error) {
+	terms, err := parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var result map[string]struct{}
+	for _, t := range terms {
+		if t.negate || t.from != nil || t.to != nil {
+			continue
+		}
+
+		ids := i.match(t)
+		if result == nil {
+			result = ids
+			continue
+		}
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	if result == nil {
+		result = make(map[string]struct{}, len(i.docs))
+		for id := range i.docs {
+			result[id] = struct{}{}
+		}
+	}
+
+	for _, t := range terms {
+		switch {
+		case t.negate:
+			for id := range i.match(t) {
+				delete(result, id)
+			}
+		case t.from != nil || t.to != nil:
+			for id := range result {
+				docTime := i.docs[id].time
+				if (t.from != nil && docTime < *t.from) || (t.to != nil && docTime >= *t.to) {
+					delete(result, id)
+				}
+			}
+		}
+	}
+
+	var ids = make([]string, 0, len(result))
+	for id := range result {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids, nil
+}
+
+// match returns documents containing every token of the term.
+func (i *Index) match(t term) map[string]struct{} {
+	var result map[string]struct{}
+	for n, token := range t.tokens {
+		var ids = make(map[string]struct{})
+		if t.prefix && n == len(t.tokens)-1 {
+			for _, k := range i.prefixKeys(key(t.field, token)) {
+				for id := range i.postings[k] {
+					ids[id] = struct{}{}
+				}
+			}
+		} else {
+			for id := range i.postings[key(t.field, token)] {
+				ids[id] = struct{}{}
+			}
+		}
+
+		if result == nil {
+			result = ids
+			continue
+		}
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	if result == nil {
+		result = make(map[string]struct{})
+	}
+
+	return result
+}
+
+func (i *Index) prefixKeys(prefix string) []string {
+	if i.keys == nil {
+		i.keys = make([]string, 0, len(i.postings))
+		for k := range i.postings {
+			i.keys = append(i.keys, k)
+		}
+		slices.Sort(i.keys)
+	}
+
+	start := sort.SearchStrings(i.keys, prefix)
+	end := start
+	for end < len(i.keys) && strings.HasPrefix(i.keys[end], prefix) {
+		end++
+	}
+
+	return i.keys[start:end]
+}
+
+type term struct {
+	field  string
+	tokens []string
+	prefix bool
+	negate bool
+	// from and to bound capture time in unix seconds, to is exclusive.
+	from *int64
+	to   *int64
+}
+
+func parse(query string) ([]term, error) {
+	var terms []term
+	for _, raw := range split(query) {
+		var t term
+
+		if strings.HasPrefix(raw, "-") && len(raw) > 1 {
+			t.negate = true
+			raw = raw[1:]
+		}
+
+		if field, value, ok := strings.Cut(raw, ":"); ok && field != "" && value != "" {
+			field = strings.ToLower(field)
+			value = strings.Trim(value, `"`)
+
+			switch field {
+			case "date", "after", "before":
+				if t.negate {
+					return nil, fmt.Errorf("negated %s: %w", field, entity.ErrInvalidInput)
+				}
+				if err := t.dates(field, value); err != nil {
+					return nil, fmt.Errorf("%s: %w", field, err)
+				}
+				terms = append(terms, t)
+				continue
+			}
+
+			t.field, raw = field, value
+		}
+
+		raw = strings.Trim(raw, `"`)
+		t.prefix = strings.HasSuffix(raw, "*")
+		t.tokens = Tokenize(strings.TrimSuffix(raw, "*"))
+		if len(t.tokens) == 0 {
+			continue
+		}
+
+		terms = append(terms, t)
+	}
+
+	return terms, nil
+}
+
+func (t *term) dates(field, value string) error {
+	switch field {
+	case "after":
+		from, _, err := parseDate(value)
+		if err != nil {
+			return err
+		}
+		t.from = &from
+	case "before":
+		to, _, err := parseDate(value)
+		if err != nil {
+			return err
+		}
+		t.to = &to
+	default:
+		first, last, isRange := strings.Cut(value, dateRange)
+		from, to, err := parseDate(first)
+		if err != nil {
+			return err
+		}
+		if isRange {
+			if _, to, err = parseDate(last); err != nil {
+				return err
+			}
+		}
+		t.from, t.to = &from, &to
+	}
+
+	return nil
+}
+
+// parseDate parses a year, month or day and returns its bounds in UTC.
+func parseDate(v string) (int64, int64, error) {
+	for _, layout := range []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	} {
+		if t, err := time.Parse(layout.layout, v); err == nil {
+			return t.Unix(), layout.next(t).Unix(), nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("date `%s`: %w", v, entity.ErrInvalidInput)
+}
+
+// split separates the query on spaces keeping double quoted values together.
+func split(query string) []string {
+	var (
+		parts  []string
+		quoted bool
+		cur    strings.Builder
+	)
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if cur.Len() > 0 {
+				parts = append(parts, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, cur.String())
+	}
+
+	return parts
+}
+
+// Tokenize lowercases the text and splits it on anything but letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func key(field, token string) string {
+	return field + keySeparator + token
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tekig/photo-backup-server/internal/entity"
+)
+
+func unix(v string) int64 {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		panic(err)
+	}
+
+	return t.Unix()
+}
+
+func newTestIndex() *Index {
+	i := New()
+	i.Put(Document{
+		ID: "a",
+		Fields: map[string][]string{
+			FieldName:   {"beach.jpg"},
+			FieldTag:    {"new year", "family"},
+			FieldCamera: {"Canon EOS R6"},
+			FieldPlace:  {"Lisbon, Portugal"},
+		},
+		Time: unix("2023-05-10T12:00:00Z"),
+	})
+	i.Put(Document{
+		ID: "b",
+		Fields: map[string][]string{
+			FieldName:   {"work.jpg"},
+			FieldTag:    {"work"},
+			FieldCamera: {"Nikon Z6"},
+			FieldPlace:  {"Porto, Portugal"},
+		},
+		Time: unix("2023-08-31T23:00:00Z"),
+	})
+	i.Put(Document{
+		ID: "c",
+		Fields: map[string][]string{
+			FieldName:    {"beachside.mov"},
+			FieldCaption: {"Sunset at the beach"},
+			FieldCamera:  {"Canon"},
+		},
+		Time: unix("2024-01-01T00:00:00Z"),
+	})
+
+	return i
+}
+
+func TestSearch(t *testing.T) {
+	i := newTestIndex()
+
+	for _, tt := range []struct {
+		query string
+		ids   []string
+		err   error
+	}{
+		{"", []string{"a", "b", "c"}, nil},
+		{"lisbon", []string{"a"}, nil},
+		{"LISBON", []string{"a"}, nil},
+		{"portugal", []string{"a", "b"}, nil},
+		{"beach", []string{"a", "c"}, nil},
+		{"beachs*", []string{"c"}, nil},
+		{"be*", []string{"a", "c"}, nil},
+		{"camera:canon", []string{"a", "c"}, nil},
+		{"camera:can*", []string{"a", "c"}, nil},
+		{"CAMERA:nikon", []string{"b"}, nil},
+		{"camera:eos", []string{"a"}, nil},
+		{"place:canon", []string{}, nil},
+		{`tag:"new year"`, []string{"a"}, nil},
+		{`tag:"year new"`, []string{"a"}, nil},
+		{"tag:new tag:work", []string{}, nil},
+		{"-tag:work", []string{"a", "c"}, nil},
+		{"canon -tag:family", []string{"c"}, nil},
+		{"unknown", []string{}, nil},
+		{"date:2023", []string{"a", "b"}, nil},
+		{"date:2023-05", []string{"a"}, nil},
+		{"date:2023-05-10", []string{"a"}, nil},
+		{"date:2023-05..2023-08", []string{"a", "b"}, nil},
+		{"date:2023-09", []string{}, nil},
+		{"after:2023-06-01", []string{"b", "c"}, nil},
+		{"before:2023-06-01", []string{"a"}, nil},
+		{"canon date:2024", []string{"c"}, nil},
+		{"-date:2023", nil, entity.ErrInvalidInput},
+		{"date:yesterday", nil, entity.ErrInvalidInput},
+		{"after:2023-13", nil, entity.ErrInvalidInput},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			ids, err := i.Search(tt.query)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && !slices.Equal(ids, tt.ids) {
+				t.Errorf("ids %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestPutReplaces(t *testing.T) {
+	i := newTestIndex()
+
+	i.Put(Document{
+		ID:     "a",
+		Fields: map[string][]string{FieldTag: {"holiday"}},
+	})
+	i.Delete("b")
+
+	for _, tt := range []struct {
+		query string
+		ids   []string
+	}{
+		{"tag:family", []string{}},
+		{"tag:hol*", []string{"a"}},
+		{"portugal", []string{}},
+		{"work", []string{}},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			ids, err := i.Search(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("ids %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}